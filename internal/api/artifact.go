@@ -0,0 +1,151 @@
+package api
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 索引版本中可以下载的目录
+var artifactDirs = []string{"output", "logs"}
+
+// artifactFile 获取索引版本 output 或 logs 目录下的文件，参数不合法时返回 400，文件不存在时返回 404
+func artifactFile(kb, db, dir, name string) (string, int, error) {
+	path, err := dbPath(kb, db)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	if dir == "" {
+		dir = "output"
+	}
+	if dir != "output" && dir != "logs" {
+		return "", http.StatusBadRequest, fmt.Errorf("dir '%s' is invalid, must be one of %s", dir, strings.Join(artifactDirs, ", "))
+	}
+	if err := checkName(name); err != nil {
+		return "", http.StatusBadRequest, err
+	}
+	file := filepath.Join(path, dir, name)
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return "", http.StatusNotFound, fmt.Errorf("file '%s' not exists", name)
+	}
+	return file, http.StatusOK, nil
+}
+
+// etagOf 由文件大小与修改时间生成 ETag，索引版本归档后文件不再变化
+func etagOf(info fs.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// DownloadArtifact 下载索引版本的单个文件（parquet、stats.json、graphml 等），支持 ETag 与 HTTP Range
+// GET 参数：kb、db、file，dir 为 output（默认）或 logs
+func (da *DataApi) DownloadArtifact(c *gin.Context) {
+	path, status, err := artifactFile(c.Query("kb"), c.Query("db"), c.Query("dir"), c.Query("file"))
+	if err != nil {
+		c.JSON(status, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+
+	// ServeContent 根据 ETag 处理 If-None-Match、If-Range 与 Range
+	c.Header("ETag", etagOf(info))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+	if filepath.Ext(path) == ".parquet" {
+		c.Header("Content-Type", "application/vnd.apache.parquet")
+	}
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), f)
+}
+
+// WriteArchive 将索引版本的 output 与 logs 写入 zip，parquet 已经压缩，直接存储
+func WriteArchive(w io.Writer, kb, db string) error {
+	path, err := dbPath(kb, db)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, dir := range artifactDirs {
+		root := filepath.Join(path, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			header.Method = zip.Deflate
+			if filepath.Ext(file) == ".parquet" {
+				header.Method = zip.Store
+			}
+			fw, err := zw.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(fw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// DownloadArchive 以 zip 流式下载索引版本的 output 与 logs
+// GET 参数：kb、db
+func (da *DataApi) DownloadArchive(c *gin.Context) {
+	kb, db := c.Query("kb"), c.Query("db")
+	if _, err := dbPath(kb, db); err != nil {
+		c.JSON(http.StatusBadRequest, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+	if db == "" {
+		db = defaultDB
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": kb + "-" + db + ".zip"}))
+	c.Status(http.StatusOK)
+	// 已开始写入响应，出错时只能中断，客户端得到不完整的 zip
+	if err := WriteArchive(c.Writer, kb, db); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// cacheMatcher 判断缓存是否属于要删除的知识库或索引版本，db 为空时匹配知识库的全部版本
+type cacheMatcher struct {
+	kb   string
+	db   string
+	root string // 以文件路径为键的缓存所在目录
+}
+
+func newCacheMatcher(kb, db string) cacheMatcher {
+	// 当前 output 位于知识库目录下，索引版本位于 versions 下
+	root := kbPath(kb)
+	switch db {
+	case "":
+	case defaultDB:
+		root = kbPath(kb, "output")
+	default:
+		root = kbPath(kb, indexVersionDir, db)
+	}
+	return cacheMatcher{kb: kb, db: db, root: root}
+}
+
+// matchDB 判断索引版本名是否匹配，空名称为当前的 output
+func (m cacheMatcher) matchDB(name string) bool {
+	if name == "" {
+		name = defaultDB
+	}
+	return m.db == "" || name == m.db
+}
+
+// matchKey 判断以 kb/db 为键的缓存是否匹配
+func (m cacheMatcher) matchKey(key string) bool {
+	name, ok := strings.CutPrefix(key, m.kb+"/")
+	return ok && m.matchDB(name)
+}
+
+// matchPath 判断以文件路径为键的缓存是否匹配
+func (m cacheMatcher) matchPath(path string) bool {
+	return strings.HasPrefix(path, m.root+string(filepath.Separator))
+}
+
+// evictCaches 删除索引版本的内存缓存，db 为空时删除知识库全部版本的缓存。
+// 删除知识库或索引版本后调用，避免缓存一直占用内存
+func evictCaches(kb, db string) {
+	m := newCacheMatcher(kb, db)
+	evictOutputTables(m)
+	evictVectorIndexes(m)
+	evictGraphs(m)
+	evictCommunities(m)
+	evictGraphStats(m)
+	evictSearchIndexes(m)
+	evictGraphDiffs(m)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestEvictCaches(t *testing.T) {
+	graphs["a/output"] = graphCache{}
+	graphs["a/v1"] = graphCache{}
+	graphs["ab/v1"] = graphCache{}
+	outputTables[kbPath("a", "output", "entities.parquet")] = outputTableCache{}
+	outputTables[kbPath("a", indexVersionDir, "v1", "output", "entities.parquet")] = outputTableCache{}
+	outputTables[kbPath("ab", "output", "entities.parquet")] = outputTableCache{}
+	graphDiffs["a/output#v1"] = graphDiffCache{}
+	graphDiffs["a/v2#v3"] = graphDiffCache{}
+	t.Cleanup(func() {
+		clear(graphs)
+		clear(outputTables)
+		clear(graphDiffs)
+	})
+
+	evictCaches("a", "v1")
+	for _, key := range []string{"a/output", "ab/v1"} {
+		if _, ok := graphs[key]; !ok {
+			t.Errorf("graph %s should not be evicted", key)
+		}
+	}
+	if _, ok := graphs["a/v1"]; ok {
+		t.Errorf("graph a/v1 should be evicted")
+	}
+	if len(outputTables) != 2 {
+		t.Errorf("got %d output tables, want 2", len(outputTables))
+	}
+	if _, ok := graphDiffs["a/output#v1"]; ok || len(graphDiffs) != 1 {
+		t.Errorf("diffs with v1 should be evicted, got %v", graphDiffs)
+	}
+
+	evictCaches("a", "")
+	if len(graphs) != 1 || len(outputTables) != 1 || len(graphDiffs) != 0 {
+		t.Errorf("caches of kb a should be evicted, got %d graphs, %d tables, %d diffs", len(graphs), len(outputTables), len(graphDiffs))
+	}
+}
+
+func TestReadOutputTable(t *testing.T) {
+	out, _ := setupSemanticKB(t)
+	type node struct {
+		Title  string `parquet:"title"`
+		Degree int64  `parquet:"degree"`
+	}
+	writeNodes := func(degree int64) {
+		nodes := []node{}
+		for _, d := range testDescriptions {
+			nodes = append(nodes, node{Title: d.title, Degree: degree})
+		}
+		if err := parquet.WriteFile(filepath.Join(out, "nodes.parquet"), nodes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNodes(1)
+
+	// 并发读取同一张表只读取一次
+	tables := make([]*OutputTable, 8)
+	wg := sync.WaitGroup{}
+	for i := range tables {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tables[i], _ = ReadOutputTable("test", "", TableEntities)
+		}()
+	}
+	wg.Wait()
+	for _, table := range tables {
+		if table == nil || table != tables[0] {
+			t.Fatalf("concurrent reads should share one table")
+		}
+	}
+	if degree := tables[0].Rows[0]["degree"]; degree != int64(1) {
+		t.Errorf("got degree %v, want 1", degree)
+	}
+
+	// 节点表改变后重新补充 degree
+	writeNodes(22)
+	table, err := ReadOutputTable("test", "", TableEntities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table == tables[0] || table.Rows[0]["degree"] != int64(22) {
+		t.Errorf("got degree %v after nodes changed, want 22", table.Rows[0]["degree"])
+	}
+}
+
+func TestTrimOutputTables(t *testing.T) {
+	t.Cleanup(func() { clear(outputTables) })
+	// 每张表占上限的一半，c 为刚读取的表，b 最久未使用
+	rows := make([]map[string]any, maxCachedTableRows/2)
+	for path, used := range map[string]uint64{"a": 3, "b": 1, "c": 2} {
+		outputTables[path] = outputTableCache{Table: &OutputTable{Rows: rows}, used: used}
+	}
+	trimOutputTables("c")
+	if _, ok := outputTables["b"]; ok || len(outputTables) != 2 {
+		t.Errorf("least recently used table b should be evicted, got %d tables", len(outputTables))
+	}
+}
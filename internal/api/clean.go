@@ -0,0 +1,443 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	rawDir = "raw" // 清洗前的原始文本，位于知识库目录下
+
+	cleanPreviewLength = 5000
+)
+
+// 清洗时机
+const (
+	CleanOff    = "off"    // 不清洗
+	CleanUpload = "upload" // 上传时清洗
+	CleanIndex  = "index"  // 建立索引前清洗
+)
+
+// 清洗步骤
+const (
+	CleanHeaderFooter = "header_footer" // 删除重复出现的页眉页脚
+	CleanPageNumber   = "page_number"   // 删除单独成行的页码
+	CleanHyphenation  = "hyphenation"   // 合并行尾连字符断开的英文单词
+	CleanLineJoin     = "line_join"     // 合并排版造成的断行
+	CleanFullWidth    = "fullwidth"     // 全角字母、数字、空格转半角
+	CleanWhitespace   = "whitespace"    // 合并多余空白与空行
+	CleanRegex        = "regex"         // 正则替换
+)
+
+var (
+	cleanModes = []string{CleanOff, CleanUpload, CleanIndex}
+	cleanSteps = []string{CleanHeaderFooter, CleanPageNumber, CleanHyphenation,
+		CleanLineJoin, CleanFullWidth, CleanWhitespace, CleanRegex}
+)
+
+// CleanOptions 文本清洗选项
+type CleanOptions struct {
+	Mode  string      `yaml:"mode" json:"mode"` // off、upload、index
+	Steps []CleanStep `yaml:"steps" json:"steps"`
+}
+
+// CleanStep 清洗步骤，按顺序执行
+type CleanStep struct {
+	Type      string `yaml:"type" json:"type"`
+	MinRepeat int    `yaml:"min_repeat,omitempty" json:"min_repeat,omitempty"` // header_footer：至少重复出现的次数，默认 3
+	MaxLength int    `yaml:"max_length,omitempty" json:"max_length,omitempty"` // header_footer：页眉页脚的最大字符数，默认 40
+	Pattern   string `yaml:"pattern,omitempty" json:"pattern,omitempty"`       // regex：正则表达式
+	Replace   string `yaml:"replace,omitempty" json:"replace,omitempty"`       // regex：替换内容，支持 $1 引用分组
+}
+
+// CleanStat 清洗步骤的统计
+type CleanStat struct {
+	Type    string `json:"type"`
+	Removed int    `json:"removed"` // 减少的字符数
+}
+
+// DefaultCleanSteps 默认清洗步骤
+func DefaultCleanSteps() []CleanStep {
+	return []CleanStep{
+		{Type: CleanHeaderFooter},
+		{Type: CleanPageNumber},
+		{Type: CleanHyphenation},
+		{Type: CleanLineJoin},
+		{Type: CleanFullWidth},
+		{Type: CleanWhitespace},
+	}
+}
+
+// Validate 校验清洗选项
+func (o *CleanOptions) Validate() error {
+	if o.Mode != "" && !slices.Contains(cleanModes, o.Mode) {
+		return fmt.Errorf("clean.mode '%s' is not one of %v", o.Mode, cleanModes)
+	}
+	for i, step := range o.Steps {
+		if !slices.Contains(cleanSteps, step.Type) {
+			return fmt.Errorf("clean.steps[%d].type '%s' is not one of %v", i, step.Type, cleanSteps)
+		}
+		if step.MinRepeat < 0 || step.MaxLength < 0 {
+			return fmt.Errorf("clean.steps[%d]: min_repeat and max_length must not be negative", i)
+		}
+		if step.Type == CleanRegex {
+			if step.Pattern == "" {
+				return fmt.Errorf("clean.steps[%d].pattern is empty", i)
+			}
+			if _, err := regexp.Compile(step.Pattern); err != nil {
+				return fmt.Errorf("clean.steps[%d].pattern: %s", i, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	pageNumberRegexp  = regexp.MustCompile(`(?m)^[ \t]*(?:第\s*\d+\s*页(?:\s*[/，,]?\s*共\s*\d+\s*页)?|[-–—]?\s*\d{1,4}\s*[-–—]?|(?i:page)\s*\d+(?:\s*(?i:of)\s*\d+)?|\d+\s*/\s*\d+)[ \t]*$\n?`)
+	hyphenationRegexp = regexp.MustCompile(`([A-Za-z])-[ \t]*\n[ \t]*([a-z])`)
+	spacesRegexp      = regexp.MustCompile(`[ \t\f\v\x{00A0}]+`)
+	zeroWidthReplacer = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\ufeff", "")
+)
+
+// CleanText 按顺序执行清洗步骤
+func CleanText(text string, steps []CleanStep) (string, []CleanStat, error) {
+	stats := []CleanStat{}
+	for _, step := range steps {
+		before := utf8.RuneCountInString(text)
+		switch step.Type {
+		case CleanHeaderFooter:
+			text = removeHeaderFooter(text, step)
+		case CleanPageNumber:
+			text = pageNumberRegexp.ReplaceAllString(text, "")
+		case CleanHyphenation:
+			text = hyphenationRegexp.ReplaceAllString(text, "$1$2")
+		case CleanLineJoin:
+			text = joinLines(text)
+		case CleanFullWidth:
+			text = toHalfWidth(text)
+		case CleanWhitespace:
+			text = cleanWhitespace(text)
+		case CleanRegex:
+			re, err := regexp.Compile(step.Pattern)
+			if err != nil {
+				return "", nil, err
+			}
+			text = re.ReplaceAllString(text, step.Replace)
+		default:
+			return "", nil, fmt.Errorf("unknown clean step '%s'", step.Type)
+		}
+		stats = append(stats, CleanStat{Type: step.Type, Removed: before - utf8.RuneCountInString(text)})
+	}
+	return text, stats, nil
+}
+
+// removeHeaderFooter 删除重复出现 min_repeat 次以上的短行，一般是每页都有的页眉页脚
+func removeHeaderFooter(text string, step CleanStep) string {
+	minRepeat, maxLength := step.MinRepeat, step.MaxLength
+	if minRepeat == 0 {
+		minRepeat = 3
+	}
+	if maxLength == 0 {
+		maxLength = 40
+	}
+
+	lines := strings.Split(text, "\n")
+	counts := map[string]int{}
+	for _, line := range lines {
+		key := strings.TrimSpace(line)
+		if key != "" && utf8.RuneCountInString(key) <= maxLength {
+			counts[key]++
+		}
+	}
+
+	kept := lines[:0]
+	for _, line := range lines {
+		if counts[strings.TrimSpace(line)] >= minRepeat {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// sentenceEnd 句末标点，以这些字符结尾的行不与下一行合并
+const sentenceEnd = "。！？；：.!?;:」』”’)）]】>》"
+
+var listItemRegexp = regexp.MustCompile(`^\s*(?:[-*•·●○■□◆]|\d+[.、)）]|[（(]\d+[)）]|[一二三四五六七八九十]+、|第[一二三四五六七八九十百\d]+[章节条款])`)
+
+// joinLines 合并排版造成的断行：行长接近正文的常见行长、且不以句末标点结尾的行与下一行合并
+func joinLines(text string) string {
+	lines := strings.Split(text, "\n")
+
+	lengths := []int{}
+	for _, line := range lines {
+		if n := lineWidth(line); n > 0 {
+			lengths = append(lengths, n)
+		}
+	}
+	if len(lengths) == 0 {
+		return text
+	}
+	// 取中位数作为正文的常见行宽，行宽不足其 80% 的视为标题或段落末行
+	sort.Ints(lengths)
+	threshold := lengths[len(lengths)/2] * 8 / 10
+
+	sb := strings.Builder{}
+	for i, line := range lines {
+		sb.WriteString(line)
+		if i == len(lines)-1 {
+			break
+		}
+
+		cur := strings.TrimRight(line, " \t")
+		next := strings.TrimLeft(lines[i+1], " \t")
+		last, _ := utf8.DecodeLastRuneInString(cur)
+		first, _ := utf8.DecodeRuneInString(next)
+		if cur == "" || next == "" ||
+			lineWidth(cur) < threshold ||
+			strings.ContainsRune(sentenceEnd, last) ||
+			listItemRegexp.MatchString(next) {
+			sb.WriteString("\n")
+			continue
+		}
+
+		// 英文单词之间补一个空格，中文直接相连
+		if last < utf8.RuneSelf && first < utf8.RuneSelf && !unicode.IsSpace(last) {
+			sb.WriteString(" ")
+		}
+		lines[i+1] = next
+	}
+	return sb.String()
+}
+
+// lineWidth 计算行的显示宽度，汉字等宽字符计为 2
+func lineWidth(line string) int {
+	n := 0
+	for _, r := range strings.TrimSpace(line) {
+		if r >= 0x1100 && (unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) ||
+			unicode.IsPunct(r) && r >= 0x3000) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// toHalfWidth 全角字母、数字与全角空格转为半角，中文标点保持不变
+func toHalfWidth(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u3000':
+			return ' '
+		case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+			return r - 0xFEE0
+		}
+		return r
+	}, text)
+}
+
+// cleanWhitespace 删除零宽字符，合并行内连续空白，去除行首尾空白，最多保留一个空行
+func cleanWhitespace(text string) string {
+	text = zeroWidthReplacer.Replace(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacesRegexp.ReplaceAllString(line, " "))
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLinesRegexp.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+// cleanTarget 获取文件对应的 graphrag 输入文本：需要转换的文件为转换后的 txt，
+// 不是 txt 的文件（如 CSV）不清洗，返回空字符串
+func cleanTarget(name string) string {
+	if needConvert(name) {
+		return convertedName(name)
+	}
+	if strings.EqualFold(filepath.Ext(name), ".txt") {
+		return name
+	}
+	return ""
+}
+
+// CleanInput 清洗单个输入文本，始终从 raw 目录中的原始文本清洗，原始文本不存在时先保存
+func CleanInput(kb, name string, steps []CleanStep) ([]CleanStat, error) {
+	path := kbPath(kb, "input", name)
+	raw := kbPath(kb, rawDir, name)
+
+	data, err := os.ReadFile(raw)
+	if os.IsNotExist(err) {
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(raw), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(raw, data); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	cleaned, stats, err := CleanText(string(data), steps)
+	if err != nil {
+		return nil, err
+	}
+	current, err := os.ReadFile(path)
+	if err == nil && string(current) == cleaned {
+		return stats, nil
+	}
+	return stats, writeFileAtomic(path, []byte(cleaned))
+}
+
+// CleanInputs 清洗知识库所有输入文本，返回清洗的文件数
+func CleanInputs(kb string) (int, error) {
+	opts, err := ReadKBOptions(kb)
+	if err != nil {
+		return 0, err
+	}
+	files, err := ReadInput(kb)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, file := range files {
+		if isConverted(kb, file) || cleanTarget(file) == "" {
+			continue
+		}
+		if _, err := CleanInput(kb, cleanTarget(file), opts.Clean.Steps); err != nil {
+			return n, fmt.Errorf("failed to clean '%s': %w", file, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// removeRaw 删除原始文本，重新上传同名文件后旧的原始文本不再有效
+func removeRaw(kb, name string) error {
+	if err := os.Remove(kbPath(kb, rawDir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PreviewClean 预览文件清洗前后的文本，可传入 steps 试验新的清洗步骤
+func (ka *KBApi) PreviewClean(c *gin.Context) {
+	type PreviewCleanReq struct {
+		KB    string      `json:"kb"`
+		File  string      `json:"file"`
+		Steps []CleanStep `json:"steps"` // 为空时使用知识库配置的清洗步骤
+	}
+	type PreviewCleanRsp struct {
+		BaseRsp
+		Before    string      `json:"before"`
+		After     string      `json:"after"`
+		Diff      string      `json:"diff"`
+		Truncated bool        `json:"truncated"` // 文本过长时只返回开头部分，diff 也只比较这一部分
+		Stats     []CleanStat `json:"stats"`
+	}
+
+	req := PreviewCleanReq{}
+	rsp := PreviewCleanRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if _, err := inputFile(req.KB, req.File); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	target := cleanTarget(req.File)
+	if target == "" {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("'%s' is not a text file", req.File)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	steps := req.Steps
+	if len(steps) == 0 {
+		opts, err := ReadKBOptions(req.KB)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		steps = opts.Clean.Steps
+	}
+	check := CleanOptions{Steps: steps}
+	if err := check.Validate(); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 优先使用原始文本，未清洗过的文件使用 input 中的文本
+	data, err := os.ReadFile(kbPath(req.KB, rawDir, target))
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(kbPath(req.KB, "input", target))
+	}
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	after, stats, err := CleanText(string(data), steps)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 只预览并比较开头的部分，在行尾截断，大文件的 diff 不必计算全文
+	truncate := func(s string) string {
+		runes := []rune(s)
+		if len(runes) <= cleanPreviewLength {
+			return s
+		}
+		rsp.Truncated = true
+		s = string(runes[:cleanPreviewLength])
+		if i := strings.LastIndexByte(s, '\n'); i > 0 {
+			s = s[:i+1]
+		}
+		return s
+	}
+	rsp.Before = truncate(string(data))
+	rsp.After = truncate(after)
+	rsp.Diff, err = unifiedDiff("raw/"+target, "input/"+target, rsp.Before, rsp.After, 3)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	rsp.Stats = stats
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
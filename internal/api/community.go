@@ -0,0 +1,558 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Community 社区，graphrag 使用层次 Leiden 算法划分，level 0 为最顶层
+type Community struct {
+	ID       string   `json:"id"` // 社区编号，即社区表的 community 列
+	Level    int      `json:"level"`
+	Parent   string   `json:"parent"` // 顶层社区为空
+	Children []string `json:"children"`
+	Title    string   `json:"title"`
+	Size     int      `json:"size"`             // 成员实体数
+	Rating   *float64 `json:"rating,omitempty"` // 报告的评分，没有报告时为空
+
+	entities      []string // 成员实体的 title
+	relationships []string // 成员关系的 ID，社区表没有 relationship_ids 时为空
+}
+
+// CommunityFinding 社区报告中的发现
+type CommunityFinding struct {
+	Summary     string `json:"summary"`
+	Explanation string `json:"explanation"`
+}
+
+// CommunityReport 社区报告
+type CommunityReport struct {
+	Community         string             `json:"community"`
+	Level             int                `json:"level"`
+	Title             string             `json:"title"`
+	Summary           string             `json:"summary"`
+	Rating            *float64           `json:"rating"`
+	RatingExplanation string             `json:"rating_explanation"`
+	Findings          []CommunityFinding `json:"findings"`
+	FullContent       string             `json:"full_content"`
+}
+
+// CommunityLevel 同一层级的社区
+type CommunityLevel struct {
+	Level       int          `json:"level"`
+	Communities []*Community `json:"communities"`
+}
+
+// Communities 索引版本的社区层次与报告
+type Communities struct {
+	List    []*Community // 按层级、编号排序
+	byID    map[string]*Community
+	reports map[string]*CommunityReport
+	byTitle map[string][]*Community // 实体 title 所属的社区，按层级排序
+}
+
+// communitiesCache 索引版本的社区，输出表重新读取或图重新构建后重新构建
+type communitiesCache struct {
+	communities *OutputTable
+	reports     *OutputTable
+	graph       *Graph
+	result      *Communities
+}
+
+var (
+	communityCaches = map[string]communitiesCache{}
+	communityMux    sync.Mutex
+)
+
+func intOf(value any) int {
+	f, _ := toFloat(value)
+	return int(f)
+}
+
+func stringsOf(value any) []string {
+	list, _ := value.([]any)
+	s := make([]string, 0, len(list))
+	for _, v := range list {
+		s = append(s, stringOf(v))
+	}
+	return s
+}
+
+// communityID 社区编号，graphrag 1.0 之前社区表没有 community 列，编号在 id 列
+func communityID(row map[string]any) string {
+	if id := stringOf(row["community"]); id != "" {
+		return id
+	}
+	return stringOf(row["id"])
+}
+
+// nodeCommunities 从节点表获取社区成员，用于社区表没有 entity_ids 列的旧版本输出，key 为社区编号
+func nodeCommunities(kb, db string) map[string][]string {
+	members := map[string][]string{}
+	nodes, err := ReadOutputTable(kb, db, TableNodes)
+	if err != nil {
+		return members
+	}
+	for _, row := range nodes.Rows {
+		id, title := stringOf(row["community"]), stringOf(row["title"])
+		if id == "" || id == "-1" || title == "" {
+			continue
+		}
+		members[id] = append(members[id], title)
+	}
+	return members
+}
+
+// buildCommunities 由社区表与报告表构建社区层次，报告表可以为空
+func buildCommunities(kb, db string, table, reports *OutputTable, g *Graph) *Communities {
+	cs := Communities{
+		byID:    map[string]*Community{},
+		reports: map[string]*CommunityReport{},
+		byTitle: map[string][]*Community{},
+	}
+
+	titles := map[string]string{}
+	for title, node := range g.Nodes {
+		if node.ID != "" {
+			titles[node.ID] = title
+		}
+	}
+	var members map[string][]string
+	if !slices.Contains(table.Columns, "entity_ids") {
+		members = nodeCommunities(kb, db)
+	}
+
+	parents := map[string]string{}
+	for _, row := range table.Rows {
+		c := Community{
+			ID:            communityID(row),
+			Level:         intOf(row["level"]),
+			Children:      []string{},
+			Title:         stringOf(row["title"]),
+			relationships: stringsOf(row["relationship_ids"]),
+		}
+		if c.ID == "" {
+			continue
+		}
+		if members != nil {
+			c.entities = members[c.ID]
+		}
+		for _, id := range stringsOf(row["entity_ids"]) {
+			if title, ok := titles[id]; ok {
+				c.entities = append(c.entities, title)
+			}
+		}
+		c.Size = len(c.entities)
+		if parent, ok := row["parent"]; ok && parent != nil {
+			parents[c.ID] = stringOf(parent)
+		}
+		cs.List = append(cs.List, &c)
+		cs.byID[c.ID] = &c
+	}
+	sort.SliceStable(cs.List, func(i, j int) bool {
+		if cs.List[i].Level != cs.List[j].Level {
+			return cs.List[i].Level < cs.List[j].Level
+		}
+		return compareValues(numberOf(cs.List[i].ID), numberOf(cs.List[j].ID)) < 0
+	})
+
+	for _, c := range cs.List {
+		for _, title := range c.entities {
+			cs.byTitle[title] = append(cs.byTitle[title], c)
+		}
+	}
+
+	// 社区表没有 parent 列时（graphrag 1.2 之前），上一层级中包含成员实体的社区即为父社区
+	for _, c := range cs.List {
+		parent, ok := parents[c.ID]
+		if !ok && c.Level > 0 && len(c.entities) > 0 {
+			for _, p := range cs.byTitle[c.entities[0]] {
+				if p.Level == c.Level-1 {
+					parent = p.ID
+				}
+			}
+		}
+		if p, ok := cs.byID[parent]; ok && p != c {
+			c.Parent = p.ID
+			p.Children = append(p.Children, c.ID)
+		}
+	}
+
+	if reports != nil {
+		for _, row := range reports.Rows {
+			r := CommunityReport{
+				Community:         communityID(row),
+				Level:             intOf(row["level"]),
+				Title:             stringOf(row["title"]),
+				Summary:           stringOf(row["summary"]),
+				RatingExplanation: stringOf(row["rank_explanation"]),
+				Findings:          []CommunityFinding{},
+				FullContent:       stringOf(row["full_content"]),
+			}
+			if rating, ok := toFloat(row["rank"]); ok {
+				r.Rating = &rating
+			}
+			findings, _ := row["findings"].([]any)
+			for _, f := range findings {
+				if m, ok := f.(map[string]any); ok {
+					r.Findings = append(r.Findings, CommunityFinding{
+						Summary:     stringOf(m["summary"]),
+						Explanation: stringOf(m["explanation"]),
+					})
+				}
+			}
+			cs.reports[r.Community] = &r
+
+			// 报告的标题由 LLM 生成，比社区表的 "Community N" 更有意义
+			if c, ok := cs.byID[r.Community]; ok {
+				if r.Title != "" {
+					c.Title = r.Title
+				}
+				c.Rating = r.Rating
+			}
+		}
+	}
+	return &cs
+}
+
+// ReadCommunities 获取索引版本的社区层次与报告，没有社区表时返回错误
+func ReadCommunities(kb, db string) (*Communities, error) {
+	g, err := ReadGraph(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	table, err := ReadOutputTable(kb, db, TableCommunities)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := ReadOutputTable(kb, db, TableCommunityReports)
+	if errors.Is(err, errTableNotFound) {
+		reports = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	key := kb + "/" + db
+	if db == "" {
+		key = kb + "/" + defaultDB
+	}
+
+	communityMux.Lock()
+	defer communityMux.Unlock()
+
+	if cache, ok := communityCaches[key]; ok && cache.communities == table && cache.reports == reports && cache.graph == g {
+		return cache.result, nil
+	}
+	cs := buildCommunities(kb, db, table, reports, g)
+	communityCaches[key] = communitiesCache{communities: table, reports: reports, graph: g, result: cs}
+	return cs, nil
+}
+
+// Get 获取社区
+func (cs *Communities) Get(id string) (*Community, error) {
+	c, ok := cs.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("community '%s' not found", id)
+	}
+	return c, nil
+}
+
+// Levels 按层级分组的社区，level 小于 0 时返回所有层级
+func (cs *Communities) Levels(level int) []CommunityLevel {
+	levels := []CommunityLevel{}
+	for _, c := range cs.List {
+		if level >= 0 && c.Level != level {
+			continue
+		}
+		if len(levels) == 0 || levels[len(levels)-1].Level != c.Level {
+			levels = append(levels, CommunityLevel{Level: c.Level, Communities: []*Community{}})
+		}
+		levels[len(levels)-1].Communities = append(levels[len(levels)-1].Communities, c)
+	}
+	return levels
+}
+
+// Report 获取社区报告
+func (cs *Communities) Report(id string) (*CommunityReport, error) {
+	if _, err := cs.Get(id); err != nil {
+		return nil, err
+	}
+	r, ok := cs.reports[id]
+	if !ok {
+		return nil, fmt.Errorf("report of community '%s' not found", id)
+	}
+	return r, nil
+}
+
+// OfEntity 获取实体所属的社区，从顶层到底层
+func (cs *Communities) OfEntity(title string) []*Community {
+	communities := cs.byTitle[title]
+	if communities == nil {
+		return []*Community{}
+	}
+	return communities
+}
+
+// CommunityMembers 社区的成员实体与关系
+type CommunityMembers struct {
+	EntityTotal       int          `json:"entity_total"`
+	Entities          []*GraphNode `json:"entities"`
+	RelationshipTotal int          `json:"relationship_total"`
+	Relationships     []GraphEdge  `json:"relationships"`
+}
+
+// Members 获取社区的成员实体（按度数倒序）与关系（按权重倒序），
+// 社区表没有 relationship_ids 时使用两端都是成员实体的关系
+func (cs *Communities) Members(g *Graph, c *Community, maxNodes, maxEdges int) *CommunityMembers {
+	m := CommunityMembers{Entities: []*GraphNode{}, Relationships: []GraphEdge{}}
+
+	member := map[string]bool{}
+	for _, title := range c.entities {
+		if node, ok := g.Nodes[title]; ok && !member[title] {
+			member[title] = true
+			m.Entities = append(m.Entities, node)
+		}
+	}
+
+	if len(c.relationships) > 0 {
+		ids := map[string]bool{}
+		for _, id := range c.relationships {
+			ids[id] = true
+		}
+		for _, e := range g.Edges {
+			if ids[e.ID] {
+				m.Relationships = append(m.Relationships, e)
+			}
+		}
+	} else {
+		for _, e := range g.Edges {
+			if member[e.Source] && member[e.Target] {
+				m.Relationships = append(m.Relationships, e)
+			}
+		}
+	}
+
+	sort.SliceStable(m.Entities, func(i, j int) bool { return m.Entities[i].Degree > m.Entities[j].Degree })
+	sort.SliceStable(m.Relationships, func(i, j int) bool { return m.Relationships[i].Weight > m.Relationships[j].Weight })
+	m.EntityTotal, m.RelationshipTotal = len(m.Entities), len(m.Relationships)
+	m.Entities = m.Entities[:min(maxNodes, len(m.Entities))]
+	m.Relationships = m.Relationships[:min(maxEdges, len(m.Relationships))]
+	return &m
+}
+
+type CommunityApi struct {
+}
+
+func (ca *CommunityApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/community")
+
+	r.POST("/tree", ca.GetTree)
+	r.POST("/report", ca.GetReport)
+	r.POST("/members", ca.GetMembers)
+	r.POST("/entity", ca.GetEntityCommunities)
+}
+
+// GetTree 按层级获取社区层次
+func (ca *CommunityApi) GetTree(c *gin.Context) {
+	type GetTreeReq struct {
+		KB    string `json:"kb"`
+		DB    string `json:"db"`
+		Level *int   `json:"level"` // 为空时返回所有层级
+	}
+	type GetTreeRsp struct {
+		BaseRsp
+		Levels []CommunityLevel `json:"levels"`
+	}
+
+	req := GetTreeReq{}
+	rsp := GetTreeRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cs, err := ReadCommunities(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	level := -1
+	if req.Level != nil {
+		level = *req.Level
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Levels = cs.Levels(level)
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetReport 获取社区报告
+func (ca *CommunityApi) GetReport(c *gin.Context) {
+	type GetReportReq struct {
+		KB        string `json:"kb"`
+		DB        string `json:"db"`
+		Community string `json:"community"`
+	}
+	type GetReportRsp struct {
+		BaseRsp
+		Report *CommunityReport `json:"report"`
+	}
+
+	req := GetReportReq{}
+	rsp := GetReportRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cs, err := ReadCommunities(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	report, err := cs.Report(req.Community)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Report = report
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetMembers 获取社区的成员实体与关系
+func (ca *CommunityApi) GetMembers(c *gin.Context) {
+	type GetMembersReq struct {
+		KB        string `json:"kb"`
+		DB        string `json:"db"`
+		Community string `json:"community"`
+		MaxNodes  int    `json:"max_nodes"`
+		MaxEdges  int    `json:"max_edges"`
+	}
+	type GetMembersRsp struct {
+		BaseRsp
+		Community *Community `json:"community"`
+		*CommunityMembers
+	}
+
+	req := GetMembersReq{}
+	rsp := GetMembersRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cs, err := ReadCommunities(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	community, err := cs.Get(req.Community)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	g, err := ReadGraph(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Community = community
+	rsp.CommunityMembers = cs.Members(g, community,
+		limitOf(req.MaxNodes, defaultSubgraphNodes, maxSubgraphNodes),
+		limitOf(req.MaxEdges, defaultSubgraphEdges, maxSubgraphEdges))
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetEntityCommunities 获取实体所属的各层级社区
+func (ca *CommunityApi) GetEntityCommunities(c *gin.Context) {
+	type GetEntityCommunitiesReq struct {
+		KB     string `json:"kb"`
+		DB     string `json:"db"`
+		Entity string `json:"entity"`
+	}
+	type GetEntityCommunitiesRsp struct {
+		BaseRsp
+		Node        *GraphNode   `json:"node"`
+		Communities []*Community `json:"communities"`
+	}
+
+	req := GetEntityCommunitiesReq{}
+	rsp := GetEntityCommunitiesRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cs, err := ReadCommunities(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	g, err := ReadGraph(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	title, err := g.Find(req.Entity)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Node = g.Nodes[title]
+	rsp.Communities = cs.OfEntity(title)
+	c.JSON(http.StatusOK, rsp)
+}
+
+// evictCommunities 删除匹配的索引版本的社区缓存
+func evictCommunities(m cacheMatcher) {
+	communityMux.Lock()
+	defer communityMux.Unlock()
+	for key := range communityCaches {
+		if m.matchKey(key) {
+			delete(communityCaches, key)
+		}
+	}
+}
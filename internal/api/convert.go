@@ -0,0 +1,330 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html"
+)
+
+// convertExtensions 上传后需要转换为纯文本的文件类型
+var convertExtensions = []string{".pdf", ".docx", ".html", ".htm", ".md", ".markdown"}
+
+// ConvertResult 文档转换结果
+type ConvertResult struct {
+	Source   string   `json:"source"`          // 原始文件名
+	Output   string   `json:"output"`          // 转换得到的 txt 文件名
+	Pages    int      `json:"pages,omitempty"` // 页数，无法获取时为 0
+	Chars    int      `json:"chars"`           // 提取的字符数
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// needConvert 判断文件是否需要转换
+func needConvert(filename string) bool {
+	return slices.Contains(convertExtensions, strings.ToLower(filepath.Ext(filename)))
+}
+
+// convertedName 转换得到的 txt 文件名，保留原扩展名以免不同格式的同名文件冲突
+func convertedName(filename string) string {
+	return filename + ".txt"
+}
+
+// ConvertDocument 将 PDF、DOCX、HTML、Markdown 转换为纯文本，保存在原文件旁
+func ConvertDocument(path string) (*ConvertResult, error) {
+	result := ConvertResult{
+		Source: filepath.Base(path),
+		Output: convertedName(filepath.Base(path)),
+	}
+
+	var text string
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		text, err = convertPDF(path, &result)
+	case ".docx":
+		text, err = convertDOCX(path, &result)
+	case ".html", ".htm":
+		text, err = convertHTML(path)
+	case ".md", ".markdown":
+		text, err = convertMarkdown(path)
+	default:
+		err = fmt.Errorf("unsupported file type: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text = normalizeText(text)
+	result.Chars = utf8.RuneCountInString(text)
+	if result.Chars == 0 {
+		result.Warnings = append(result.Warnings, "no text extracted")
+	}
+
+	output := filepath.Join(filepath.Dir(path), result.Output)
+	if err := writeFileAtomic(output, []byte(text)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+var blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+
+// normalizeText 统一换行并去除多余空行
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t ")
+	}
+	text = strings.Join(lines, "\n")
+	text = blankLinesRegexp.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text) + "\n"
+}
+
+// convertPDF 提取 PDF 文本层
+func convertPDF(path string, result *ConvertResult) (text string, err error) {
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open pdf: %w", err)
+	}
+	defer f.Close()
+
+	// 解析异常的 PDF 可能导致 panic
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("failed to parse pdf: %v", e)
+		}
+	}()
+
+	result.Pages = r.NumPage()
+	sb := strings.Builder{}
+	empty := []string{}
+	fonts := map[string]*pdf.Font{}
+	for i := 1; i <= result.Pages; i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+		content, err := page.GetPlainText(fonts)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("page %d: %s", i, err.Error()))
+			continue
+		}
+		if strings.TrimSpace(content) == "" {
+			empty = append(empty, strconv.Itoa(i))
+			continue
+		}
+		sb.WriteString(content)
+		sb.WriteString("\n\n")
+	}
+
+	switch {
+	case result.Pages > 0 && len(empty) == result.Pages:
+		result.Warnings = append(result.Warnings, "scanned PDF, no text layer")
+	case len(empty) > 0:
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("pages without text layer: %s", strings.Join(empty, ",")))
+	}
+	return sb.String(), nil
+}
+
+// convertDOCX 提取 DOCX 正文段落
+func convertDOCX(path string, result *ConvertResult) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open docx: %w", err)
+	}
+	defer zr.Close()
+
+	var document *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			document = f
+		case "docProps/app.xml":
+			result.Pages = docxPages(f)
+		}
+	}
+	if document == nil {
+		return "", fmt.Errorf("word/document.xml not found, not a docx file")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	sb := strings.Builder{}
+	dec := xml.NewDecoder(rc)
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse docx: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br", "cr":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteString("\n")
+			case "tc":
+				sb.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// docxPages 读取 docProps/app.xml 中记录的页数
+func docxPages(f *zip.File) int {
+	rc, err := f.Open()
+	if err != nil {
+		return 0
+	}
+	defer rc.Close()
+
+	props := struct {
+		Pages int `xml:"Pages"`
+	}{}
+	if err := xml.NewDecoder(rc).Decode(&props); err != nil {
+		return 0
+	}
+	return props.Pages
+}
+
+// htmlBlockTags 块级元素，结束时换行
+var htmlBlockTags = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"section": true, "article": true, "header": true, "footer": true, "title": true,
+	"blockquote": true, "pre": true, "ul": true, "ol": true, "hr": true,
+}
+
+// convertHTML 提取 HTML 可见文本
+func convertHTML(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sb := strings.Builder{}
+	z := html.NewTokenizer(f)
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return sb.String(), nil
+			}
+			return "", fmt.Errorf("failed to parse html: %w", z.Err())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "template":
+				skip++
+			case "br", "hr":
+				sb.WriteString("\n")
+			case "td", "th":
+				sb.WriteString("\t")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style" || tag == "noscript" || tag == "template":
+				if skip > 0 {
+					skip--
+				}
+			case htmlBlockTags[tag]:
+				sb.WriteString("\n")
+			}
+		case html.TextToken:
+			if skip == 0 {
+				text := strings.Join(strings.Fields(string(z.Text())), " ")
+				sb.WriteString(text)
+			}
+		}
+	}
+}
+
+var (
+	mdFenceRegexp    = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	mdHeadingRegexp  = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`)
+	mdImageRegexp    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkRegexp     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdEmphasisRegexp = regexp.MustCompile(`(\*\*|__|\*|_|~~)([^*_~\n]+)(\*\*|__|\*|_|~~)`)
+	mdCodeRegexp     = regexp.MustCompile("`([^`]*)`")
+	mdQuoteRegexp    = regexp.MustCompile(`(?m)^\s{0,3}>\s?`)
+	mdListRegexp     = regexp.MustCompile(`(?m)^(\s*)([-*+]|\d+\.)\s+`)
+	mdRuleRegexp     = regexp.MustCompile(`(?m)^\s{0,3}([-*_]\s*){3,}$`)
+	mdTableRegexp    = regexp.MustCompile(`(?m)^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdTagRegexp      = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+)
+
+// convertMarkdown 去除 Markdown 标记
+func convertMarkdown(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	text := string(data)
+	text = mdFenceRegexp.ReplaceAllString(text, "")
+	text = mdRuleRegexp.ReplaceAllString(text, "")
+	text = mdTableRegexp.ReplaceAllString(text, "")
+	text = mdHeadingRegexp.ReplaceAllString(text, "")
+	text = mdQuoteRegexp.ReplaceAllString(text, "")
+	text = mdListRegexp.ReplaceAllString(text, "$1")
+	text = mdImageRegexp.ReplaceAllString(text, "$1")
+	text = mdLinkRegexp.ReplaceAllString(text, "$1")
+	text = mdEmphasisRegexp.ReplaceAllString(text, "$2")
+	text = mdCodeRegexp.ReplaceAllString(text, "$1")
+	text = mdTagRegexp.ReplaceAllString(text, "")
+
+	// 表格行的分隔符替换为制表符
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "|") && strings.HasSuffix(trimmed, "|") {
+			lines[i] = strings.ReplaceAll(strings.Trim(trimmed, "|"), "|", "\t")
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,388 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csvSampleRows = 5
+	csvMaxIssues  = 100
+
+	// csvFilePattern CSV 模式下 graphrag 读取的输入文件
+	csvFilePattern = `.*\.csv$`
+)
+
+// CSVColumn CSV 列信息
+type CSVColumn struct {
+	Name     string `json:"name"`
+	NonEmpty int    `json:"non_empty"` // 非空值的行数
+	Unique   int    `json:"unique"`    // 不同取值的数量
+	AvgChars int    `json:"avg_chars"` // 非空值的平均字符数
+}
+
+// CSVInfo CSV 文件的列与示例数据
+type CSVInfo struct {
+	File    string      `json:"file"`
+	Rows    int         `json:"rows"`
+	Columns []CSVColumn `json:"columns"`
+	Sample  [][]string  `json:"sample"`
+
+	// 根据列内容推测的映射：平均长度最长的列作为文本列，取值唯一的短文本列作为标题列
+	SuggestedText  string `json:"suggested_text,omitempty"`
+	SuggestedTitle string `json:"suggested_title,omitempty"`
+}
+
+// CSVMapping CSV 列映射，对应 settings.yaml 中 input 的 text_column、title_column 与 document_attribute_columns
+type CSVMapping struct {
+	TextColumn       string   `json:"text_column"`
+	TitleColumn      string   `json:"title_column"`
+	AttributeColumns []string `json:"attribute_columns"`
+}
+
+// CSVIssue CSV 校验问题，Row 为数据行号（不含表头，从 1 开始），0 表示整个文件
+type CSVIssue struct {
+	File string `json:"file"`
+	Row  int    `json:"row"`
+	Msg  string `json:"msg"`
+}
+
+func openCSV(path string) (*os.File, *csv.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return f, r, nil
+}
+
+// ReadCSVInfo 读取 CSV 文件的列与示例数据
+func ReadCSVInfo(kb, name string) (*CSVInfo, error) {
+	if !strings.EqualFold(filepath.Ext(name), ".csv") {
+		return nil, fmt.Errorf("'%s' is not a csv file", name)
+	}
+	path, err := inputFile(kb, name)
+	if err != nil {
+		return nil, err
+	}
+
+	f, r, err := openCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	info := CSVInfo{File: name, Sample: [][]string{}}
+	chars := make([]int, len(header))
+	uniques := make([]map[string]bool, len(header))
+	for i, col := range header {
+		info.Columns = append(info.Columns, CSVColumn{Name: strings.TrimSpace(col)})
+		uniques[i] = map[string]bool{}
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv row %d: %w", info.Rows+1, err)
+		}
+		info.Rows++
+		if len(info.Sample) < csvSampleRows {
+			info.Sample = append(info.Sample, record)
+		}
+		for i := range info.Columns {
+			if i >= len(record) || strings.TrimSpace(record[i]) == "" {
+				continue
+			}
+			info.Columns[i].NonEmpty++
+			chars[i] += utf8.RuneCountInString(record[i])
+			uniques[i][record[i]] = true
+		}
+	}
+
+	longest := 0
+	for i := range info.Columns {
+		col := &info.Columns[i]
+		col.Unique = len(uniques[i])
+		if col.NonEmpty > 0 {
+			col.AvgChars = chars[i] / col.NonEmpty
+		}
+		if col.AvgChars > longest {
+			longest = col.AvgChars
+			info.SuggestedText = col.Name
+		}
+	}
+	for _, col := range info.Columns {
+		if col.Name != info.SuggestedText && col.NonEmpty == info.Rows && col.Unique == info.Rows &&
+			col.AvgChars > 0 && col.AvgChars <= 100 {
+			info.SuggestedTitle = col.Name
+			break
+		}
+	}
+	return &info, nil
+}
+
+// csvInputs 获取知识库中的 CSV 输入文件
+func csvInputs(kb string) ([]string, error) {
+	files, err := ReadInput(kb)
+	if err != nil {
+		return nil, err
+	}
+	csvs := []string{}
+	for _, file := range files {
+		if strings.EqualFold(filepath.Ext(file), ".csv") {
+			csvs = append(csvs, file)
+		}
+	}
+	return csvs, nil
+}
+
+// ValidateCSVInputs 校验知识库所有 CSV 文件都包含映射的列，且每行文本列非空
+func ValidateCSVInputs(kb string, m CSVMapping) ([]CSVIssue, error) {
+	files, err := csvInputs(kb)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return []CSVIssue{{Msg: "no csv file in input"}}, nil
+	}
+
+	issues := []CSVIssue{}
+	add := func(file string, row int, format string, a ...any) bool {
+		issues = append(issues, CSVIssue{File: file, Row: row, Msg: fmt.Sprintf(format, a...)})
+		return len(issues) < csvMaxIssues
+	}
+
+	for _, file := range files {
+		more, err := validateCSVFile(kb, file, m, add)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			issues = append(issues, CSVIssue{Msg: fmt.Sprintf("too many issues, only the first %d are shown", csvMaxIssues)})
+			break
+		}
+	}
+	return issues, nil
+}
+
+// validateCSVFile 校验单个 CSV 文件，add 返回 false 时停止校验
+func validateCSVFile(kb, file string, m CSVMapping, add func(string, int, string, ...any) bool) (bool, error) {
+	f, r, err := openCSV(kbPath(kb, "input", file))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header, err := r.Read()
+	if err != nil {
+		return add(file, 0, "failed to read header: %s", err.Error()), nil
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	columns := append([]string{m.TextColumn}, m.AttributeColumns...)
+	if m.TitleColumn != "" {
+		columns = append(columns, m.TitleColumn)
+	}
+	missing := false
+	for _, col := range columns {
+		if !slices.Contains(header, col) {
+			missing = true
+			if !add(file, 0, "column '%s' not found", col) {
+				return false, nil
+			}
+		}
+	}
+	if missing {
+		return true, nil
+	}
+
+	text := slices.Index(header, m.TextColumn)
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return add(file, row, "%s", parseErr.Err.Error()), nil
+			}
+			return false, err
+		}
+		if len(record) != len(header) {
+			if !add(file, row, "expected %d fields, got %d", len(header), len(record)) {
+				return false, nil
+			}
+			continue
+		}
+		if strings.TrimSpace(record[text]) == "" {
+			if !add(file, row, "text column '%s' is empty", m.TextColumn) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// UpdateCSVMapping 校验 CSV 文件并将知识库切换为 CSV 输入模式
+// 映射与文件的问题通过 issues、配置校验失败通过 errs 返回，error 只表示读写失败
+func UpdateCSVMapping(kb string, m CSVMapping) ([]CSVIssue, []string, error) {
+	if m.TextColumn == "" {
+		return []CSVIssue{{Msg: "text_column is empty"}}, nil, nil
+	}
+
+	issues, err := ValidateCSVInputs(kb, m)
+	if err != nil || len(issues) > 0 {
+		return issues, nil, err
+	}
+
+	attributes := m.AttributeColumns
+	if attributes == nil {
+		attributes = []string{}
+	}
+	input := map[string]any{
+		"file_type":                  "csv",
+		"file_pattern":               csvFilePattern,
+		"text_column":                m.TextColumn,
+		"document_attribute_columns": attributes,
+	}
+	if m.TitleColumn != "" {
+		input["title_column"] = m.TitleColumn
+	} else {
+		input["title_column"] = nil
+	}
+
+	_, errs, err := PatchSettingsValues(kb, map[string]any{"input": input})
+	return nil, errs, err
+}
+
+// csvMappingOf 从知识库配置中获取 CSV 列映射，非 CSV 模式时返回 nil
+func csvMappingOf(cfg *GraphRAGConfig) *CSVMapping {
+	if cfg.Input == nil || cfg.Input.FileType != "csv" {
+		return nil
+	}
+	m := CSVMapping{
+		TextColumn:       cfg.Input.TextColumn,
+		TitleColumn:      cfg.Input.TitleColumn,
+		AttributeColumns: cfg.Input.DocumentAttributeColumns,
+	}
+	// graphrag 默认的文本列为 text
+	if m.TextColumn == "" {
+		m.TextColumn = "text"
+	}
+	return &m
+}
+
+// GetCSVColumns 获取 CSV 文件的列与示例数据
+func (ka *KBApi) GetCSVColumns(c *gin.Context) {
+	type GetCSVColumnsReq struct {
+		KB   string `json:"kb"`
+		File string `json:"file"`
+	}
+	type GetCSVColumnsRsp struct {
+		BaseRsp
+		Info    *CSVInfo    `json:"info"`
+		Mapping *CSVMapping `json:"mapping"` // 当前的列映射，非 CSV 模式时为空
+	}
+
+	req := GetCSVColumnsReq{}
+	rsp := GetCSVColumnsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	info, err := ReadCSVInfo(req.KB, req.File)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	cfg, err := ReadSettings(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Info = info
+	rsp.Mapping = csvMappingOf(cfg)
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UpdateCSVMapping 设置 CSV 列映射，校验通过后修改 settings.yaml 的 input 配置
+func (ka *KBApi) UpdateCSVMapping(c *gin.Context) {
+	type UpdateCSVMappingReq struct {
+		KB string `json:"kb"`
+		CSVMapping
+	}
+	type UpdateCSVMappingRsp struct {
+		BaseRsp
+		Issues []CSVIssue `json:"issues,omitempty"`
+		Errors []string   `json:"errors,omitempty"`
+	}
+
+	req := UpdateCSVMappingReq{}
+	rsp := UpdateCSVMappingRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	issues, errs, err := UpdateCSVMapping(req.KB, req.CSVMapping)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if len(issues) > 0 || len(errs) > 0 {
+		rsp.Code = -1
+		rsp.Msg = "validation failed"
+		rsp.Issues = issues
+		rsp.Errors = errs
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
@@ -2,9 +2,10 @@ package api
 
 import (
 	"fmt"
-	"graphraggo/internal/global"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,9 +20,19 @@ func (da *DataApi) Register(rg *gin.RouterGroup) {
 	r.POST("/output", da.GetOutput)
 	r.POST("/delete", da.DeleteData)
 	r.POST("/logs", da.GetLogs)
+	r.GET("/logs/follow", da.FollowLogs)
+	r.POST("/diff", da.DiffData)
+	r.GET("/download", da.DownloadArtifact)
+	r.GET("/archive", da.DownloadArchive)
+	r.POST("/entities", da.GetEntities)
+	r.POST("/relationships", da.GetRelationships)
+	r.POST("/communities", da.GetCommunities)
+	r.POST("/community_reports", da.GetCommunityReports)
+	r.POST("/text_units", da.GetTextUnits)
+	r.POST("/documents", da.GetDocuments)
 }
 
-// DeleteData 删除知识库
+// DeleteData 删除索引输出，移入回收站
 func (da *DataApi) DeleteData(c *gin.Context) {
 	type DeleteDataReq struct {
 		KB   string `json:"kb"`
@@ -40,10 +51,21 @@ func (da *DataApi) DeleteData(c *gin.Context) {
 		return
 	}
 
-	// 判断文件夹是否存在
-	path := fmt.Sprintf("%s/%s/%s/output",
-		global.WorkDir, global.KBDir, req.KB)
-	_, err := os.Stat(path)
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 判断文件夹是否存在，索引版本删除整个版本目录
+	path, err := dbPath(req.KB, req.Name)
+	if err == nil && (req.Name == "" || req.Name == defaultDB) {
+		path = filepath.Join(path, "output")
+	}
+	if err == nil {
+		_, err = os.Stat(path)
+	}
 	if err != nil {
 		// 不存在
 		rsp.Code = -1
@@ -52,21 +74,55 @@ func (da *DataApi) DeleteData(c *gin.Context) {
 		return
 	}
 
-	if err := os.RemoveAll(path); err != nil {
+	// 移入回收站
+	if _, err := MoveToTrash(TrashOutput, req.KB, req.Name, path, operator(c)); err != nil {
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
 		return
 	}
+	evictCaches(req.KB, req.Name)
 
 	rsp.Code = 0
 	rsp.Msg = "success"
 	c.JSON(http.StatusOK, rsp)
 }
 
-// ReadData 获取所有 Data
+// ReadData 获取所有 Data：当前的 output 与归档的索引版本，索引版本按时间倒序
 func ReadData(kb string) ([]string, error) {
-	return []string{"output"}, nil
+	dbs := []string{defaultDB}
+
+	entries, err := os.ReadDir(kbPath(kb, indexVersionDir))
+	if os.IsNotExist(err) {
+		return dbs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].IsDir() && !strings.HasPrefix(entries[i].Name(), ".") {
+			dbs = append(dbs, entries[i].Name())
+		}
+	}
+	return dbs, nil
+}
+
+// dbPath 获取 Data 所在目录，其中包含 output 与 logs，db 为空时为当前的 output
+func dbPath(kb, db string) (string, error) {
+	if err := checkKB(kb); err != nil {
+		return "", err
+	}
+	if db == "" || db == defaultDB {
+		return kbPath(kb), nil
+	}
+	if err := checkName(db); err != nil {
+		return "", err
+	}
+	path := kbPath(kb, indexVersionDir, db)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("db '%s' not exists", db)
+	}
+	return path, nil
 }
 
 // GetData 获取可用 Data
@@ -105,8 +161,10 @@ func (da *DataApi) GetData(c *gin.Context) {
 
 // ReadOutput 获取所有 Output
 func ReadOutput(kb, db string) ([]string, error) {
-	path := fmt.Sprintf("%s/%s/%s/output",
-		global.WorkDir, global.KBDir, kb)
+	path, err := outputPath(kb, db)
+	if err != nil {
+		return nil, err
+	}
 
 	files, err := os.ReadDir(path)
 	if err != nil {
@@ -158,52 +216,3 @@ func (da *DataApi) GetOutput(c *gin.Context) {
 	rsp.Files = files
 	c.JSON(http.StatusOK, rsp)
 }
-
-// ReadLogs 获取日志文件内容
-func ReadLogs(kb, db string) ([]byte, error) {
-	filename := "indexing-engine.log"
-	logFilePath := fmt.Sprintf("%s/%s/%s/logs/%s",
-		global.WorkDir, global.KBDir, kb, filename)
-
-	files, err := os.ReadFile(logFilePath)
-	if err != nil {
-		return nil, err
-	}
-
-	return files, nil
-}
-
-// GetLogs 获取日志文件
-func (da *DataApi) GetLogs(c *gin.Context) {
-	type GetDataReq struct {
-		KB string `json:"kb"`
-		DB string `json:"db"`
-	}
-	type GetDataRsp struct {
-		BaseRsp
-		Files string `json:"files"`
-	}
-
-	req := GetDataReq{}
-	rsp := GetDataRsp{}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		rsp.Code = -1
-		rsp.Msg = err.Error()
-		c.JSON(http.StatusBadRequest, rsp)
-		return
-	}
-
-	files, err := ReadLogs(req.KB, req.DB)
-	if err != nil {
-		rsp.Code = -1
-		rsp.Msg = err.Error()
-		c.JSON(http.StatusInternalServerError, rsp)
-		return
-	}
-
-	rsp.Code = 0
-	rsp.Msg = "success"
-	rsp.Files = string(files)
-	c.JSON(http.StatusOK, rsp)
-}
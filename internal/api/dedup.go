@@ -0,0 +1,280 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	inputHashFile = "input_hashes.json"
+	inputLinkFile = "input_links.json"
+)
+
+// 重复文件的处理策略
+const (
+	DuplicateReject = "reject" // 拒绝上传
+	DuplicateLink   = "link"   // 不保存文件，记录为原文件的链接
+	DuplicateWarn   = "warn"   // 保存文件并返回警告
+)
+
+var duplicatePolicies = []string{DuplicateReject, DuplicateLink, DuplicateWarn}
+
+// DuplicateInfo 上传文件与已有文件重复
+type DuplicateInfo struct {
+	Of     string `json:"of"`     // 内容相同的已有文件
+	Policy string `json:"policy"` // 采用的处理策略
+}
+
+// DuplicateGroup 内容相同的一组输入文件
+type DuplicateGroup struct {
+	Hash  string   `json:"hash"`
+	Files []string `json:"files"`
+}
+
+// inputHash 输入文件的内容哈希缓存，文件大小与修改时间不变时复用
+type inputHash struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// contentHash 计算文本归一化后的哈希：NFKC 归一化（全角转半角等），并将连续空白合并为一个空格
+// 没有文本（如扫描版 PDF）时返回空字符串，不参与去重
+func contentHash(text string) string {
+	text = norm.NFKC.String(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// fileContentHash 计算输入文件的内容哈希，需要转换的文件使用转换后的文本，
+// 这样同一文档的 PDF、Markdown 与 txt 版本也能识别为重复
+func fileContentHash(path string) (string, error) {
+	if needConvert(path) {
+		path = filepath.Join(filepath.Dir(path), convertedName(filepath.Base(path)))
+	}
+	// 清洗过的文件使用清洗前的原始文本，与新上传的文件比较
+	raw := filepath.Join(filepath.Dir(filepath.Dir(path)), rawDir, filepath.Base(path))
+	if _, err := os.Stat(raw); err == nil {
+		path = raw
+	}
+	text, _, err := readInputText(path)
+	if err != nil {
+		return "", err
+	}
+	return contentHash(text), nil
+}
+
+// isConverted 判断是否为转换得到的 txt，这些文件与原文件一起参与去重
+func isConverted(kb, name string) bool {
+	source := strings.TrimSuffix(name, ".txt")
+	if source == name || !needConvert(source) {
+		return false
+	}
+	_, err := os.Stat(kbPath(kb, "input", source))
+	return err == nil
+}
+
+// InputHashes 获取知识库所有输入文件的内容哈希
+func InputHashes(kb string) (map[string]string, error) {
+	files, err := ReadInput(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := map[string]inputHash{}
+	if data, err := os.ReadFile(kbPath(kb, inputHashFile)); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+
+	hashes := map[string]string{}
+	updated := map[string]inputHash{}
+	for _, file := range files {
+		if isConverted(kb, file) {
+			continue
+		}
+		path := kbPath(kb, "input", file)
+		stat, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		h, ok := cache[file]
+		if !ok || h.Size != stat.Size() || !h.ModTime.Equal(stat.ModTime()) {
+			hash, err := fileContentHash(path)
+			if err != nil {
+				// 无法读取文本的文件不参与去重
+				continue
+			}
+			h = inputHash{Size: stat.Size(), ModTime: stat.ModTime(), Hash: hash}
+		}
+		updated[file] = h
+		if h.Hash != "" {
+			hashes[file] = h.Hash
+		}
+	}
+
+	if data, err := json.MarshalIndent(updated, "", "  "); err == nil {
+		writeFileAtomic(kbPath(kb, inputHashFile), data)
+	}
+	return hashes, nil
+}
+
+// findDuplicate 查找与 hash 内容相同的已有文件，name 同名文件（覆盖上传）除外
+func findDuplicate(kb, name, hash string) (string, error) {
+	if hash == "" {
+		return "", nil
+	}
+	hashes, err := InputHashes(kb)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, 0, len(hashes))
+	for file := range hashes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if file != name && hashes[file] == hash {
+			return file, nil
+		}
+	}
+	return "", nil
+}
+
+// ReadInputLinks 获取重复上传时记录的链接，key 为上传的文件名，value 为原文件名
+func ReadInputLinks(kb string) (map[string]string, error) {
+	links := map[string]string{}
+	data, err := os.ReadFile(kbPath(kb, inputLinkFile))
+	if os.IsNotExist(err) {
+		return links, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// updateInputLinks 修改链接，original 为空时删除
+func updateInputLinks(kb, name, original string) error {
+	links, err := ReadInputLinks(kb)
+	if err != nil {
+		return err
+	}
+	if original == "" {
+		if _, ok := links[name]; !ok {
+			return nil
+		}
+		delete(links, name)
+	} else {
+		links[name] = original
+	}
+
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(kbPath(kb, inputLinkFile), data)
+}
+
+// ReadDuplicateGroups 获取知识库中内容相同的输入文件
+func ReadDuplicateGroups(kb string) ([]DuplicateGroup, error) {
+	hashes, err := InputHashes(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := map[string][]string{}
+	for file, hash := range hashes {
+		byHash[hash] = append(byHash[hash], file)
+	}
+
+	groups := []DuplicateGroup{}
+	for hash, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+		sort.Strings(files)
+		groups = append(groups, DuplicateGroup{Hash: hash, Files: files})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Files[0] < groups[j].Files[0]
+	})
+	return groups, nil
+}
+
+// GetDuplicates 获取知识库中重复的输入文件，以及重复上传时记录的链接
+func (ka *KBApi) GetDuplicates(c *gin.Context) {
+	type GetDuplicatesReq struct {
+		KB string `json:"kb"`
+	}
+	type GetDuplicatesRsp struct {
+		BaseRsp
+		Groups []DuplicateGroup  `json:"groups"`
+		Links  map[string]string `json:"links"`
+	}
+
+	req := GetDuplicatesReq{}
+	rsp := GetDuplicatesRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	groups, err := ReadDuplicateGroups(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	links, err := ReadInputLinks(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Groups = groups
+	rsp.Links = links
+	c.JSON(http.StatusOK, rsp)
+}
+
+// checkDuplicatePolicy 校验重复文件处理策略
+func checkDuplicatePolicy(policy string) error {
+	if slices.Contains(duplicatePolicies, policy) {
+		return nil
+	}
+	return fmt.Errorf("upload.duplicate '%s' is not one of %v", policy, duplicatePolicies)
+}
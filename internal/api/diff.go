@@ -0,0 +1,234 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffLine 行级差异
+type DiffLine struct {
+	Op   string `json:"op"` // " " 相同, "-" 删除, "+" 新增
+	Text string `json:"text"`
+}
+
+// diff 的规模上限，时间复杂度为 O((N+M)·D)，超过时不计算
+const (
+	maxDiffLines = 50000 // 每侧的行数
+	maxDiffEdits = 4000  // 编辑距离（删除与新增的行数之和）
+)
+
+var errDiffTooLarge = fmt.Errorf("diff too large, more than %d lines or %d changed lines", maxDiffLines, maxDiffEdits)
+
+// diffLines 使用 Myers 线性空间算法计算两组文本行的最短编辑脚本，
+// 每次查找中间蛇形后分治，内存占用与行数成正比。maxEdits 大于 0 时，
+// 编辑距离明显超过 maxEdits 则返回 errDiffTooLarge
+func diffLines(a, b []string, maxEdits int) ([]DiffLine, error) {
+	size := len(a) + len(b) + 3
+	d := differ{a: a, b: b, vf: make([]int, 2*size), vb: make([]int, 2*size), limit: (maxEdits + 1) / 2}
+	d.diff(0, len(a), 0, len(b))
+	if d.exceeded {
+		return nil, errDiffTooLarge
+	}
+	return d.lines, nil
+}
+
+type differ struct {
+	a, b     []string
+	vf, vb   []int // 前向与反向搜索每条对角线到达的最远 x，下标偏移 len(vf)/2
+	limit    int   // 中间蛇形搜索步数的上限，为编辑距离上限的一半，0 表示不限制
+	exceeded bool
+	lines    []DiffLine
+}
+
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	if d.exceeded {
+		return
+	}
+
+	// 相同的前缀与后缀不参与搜索
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.lines = append(d.lines, DiffLine{Op: " ", Text: d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		for _, text := range d.b[b0:b1] {
+			d.lines = append(d.lines, DiffLine{Op: "+", Text: text})
+		}
+	case b0 == b1:
+		for _, text := range d.a[a0:a1] {
+			d.lines = append(d.lines, DiffLine{Op: "-", Text: text})
+		}
+	default:
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.diff(a0, x, b0, y)
+		for _, text := range d.a[x:u] {
+			d.lines = append(d.lines, DiffLine{Op: " ", Text: text})
+		}
+		d.diff(u, a1, v, b1)
+	}
+
+	for _, text := range d.a[a1 : a1+suffix] {
+		d.lines = append(d.lines, DiffLine{Op: " ", Text: text})
+	}
+}
+
+// middleSnake 同时从两端搜索，返回最短编辑脚本中间的蛇形 [x, u) × [y, v)，
+// 前后两部分的编辑距离均小于整体，可以分别递归
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	offset := len(d.vf) / 2
+	vf, vb := d.vf, d.vb
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	for D := 0; D <= (n+m+1)/2; D++ {
+		if d.limit > 0 && D > d.limit {
+			d.exceeded = true
+			return a0, b0, a0, b0
+		}
+		// 前向：从 (a0, b0) 出发，对角线 k = x - y
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			sx := x
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			// 反向对角线为 delta - k，与已完成 D-1 步的反向路径重叠
+			if odd && delta-k >= -(D-1) && delta-k <= D-1 && x+vb[offset+delta-k] >= n {
+				return a0 + sx, b0 + sx - k, a0 + x, b0 + y
+			}
+		}
+		// 反向：从 (a1, b1) 出发，x、y 为距末尾的行数
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			sx := x
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if !odd && delta-k >= -D && delta-k <= D && x+vf[offset+delta-k] >= n {
+				return a1 - x, b1 - y, a1 - sx, b1 - sx + k
+			}
+		}
+	}
+	panic("diff: middle snake not found")
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// unifiedDiff 生成 unified 格式的文本差异，context 为上下文行数，
+// 文本行数或差异超过上限时返回 errDiffTooLarge
+func unifiedDiff(fromName, toName, from, to string, context int) (string, error) {
+	a, b := splitLines(from), splitLines(to)
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		return "", errDiffTooLarge
+	}
+	lines, err := diffLines(a, b, maxDiffEdits)
+	if err != nil {
+		return "", err
+	}
+
+	changed := false
+	for _, l := range lines {
+		if l.Op != " " {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	i := 0
+	for i < len(lines) {
+		// 找到下一处修改
+		for i < len(lines) && lines[i].Op == " " {
+			i++
+		}
+		if i >= len(lines) {
+			break
+		}
+
+		// 扩展为包含上下文的 hunk，相距较近的修改合并到同一个 hunk
+		start := max(i-context, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != " " {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].Op == " " {
+				next++
+			}
+			if next >= len(lines) || next-end > 2*context {
+				end = min(end+context, len(lines))
+				break
+			}
+			end = next
+		}
+
+		// 计算 hunk 在两侧的起始行号与行数
+		fromLine, toLine := 1, 1
+		for _, l := range lines[:start] {
+			if l.Op != "+" {
+				fromLine++
+			}
+			if l.Op != "-" {
+				toLine++
+			}
+		}
+		fromCount, toCount := 0, 0
+		for _, l := range lines[start:end] {
+			if l.Op != "+" {
+				fromCount++
+			}
+			if l.Op != "-" {
+				toCount++
+			}
+		}
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+		for _, l := range lines[start:end] {
+			sb.WriteString(l.Op)
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+		i = end
+	}
+
+	return sb.String(), nil
+}
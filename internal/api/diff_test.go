@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcsLength 动态规划计算最长公共子序列长度，用于校验编辑脚本最短
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestDiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		lines, err := diffLines(a, b, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		from, to, same := []string{}, []string{}, 0
+		for _, l := range lines {
+			if l.Op != "+" {
+				from = append(from, l.Text)
+			}
+			if l.Op != "-" {
+				to = append(to, l.Text)
+			}
+			if l.Op == " " {
+				same++
+			}
+		}
+		if strings.Join(from, "\n") != strings.Join(a, "\n") || strings.Join(to, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diff of %q and %q does not reproduce the inputs: %v", a, b, lines)
+		}
+		if want := lcsLength(a, b); same != want {
+			t.Fatalf("diff of %q and %q keeps %d lines, want %d", a, b, same, want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\n"
+	to := "a\nc\nd\ne\n"
+	want := "--- x\n+++ y\n@@ -1,4 +1,4 @@\n a\n-b\n c\n d\n+e\n"
+	if got, err := unifiedDiff("x", "y", from, to, 3); err != nil || got != want {
+		t.Errorf("unifiedDiff = %q, %v, want %q", got, err, want)
+	}
+	if got, err := unifiedDiff("x", "y", from, from, 3); err != nil || got != "" {
+		t.Errorf("unifiedDiff of equal texts = %q, %v, want empty", got, err)
+	}
+}
+
+func TestUnifiedDiffTooLarge(t *testing.T) {
+	from, to := strings.Builder{}, strings.Builder{}
+	for i := 0; i < maxDiffEdits; i++ {
+		fmt.Fprintf(&from, "a%d\n", i)
+		fmt.Fprintf(&to, "b%d\n", i)
+	}
+	if _, err := unifiedDiff("x", "y", from.String(), to.String(), 3); err != errDiffTooLarge {
+		t.Errorf("unifiedDiff error = %v, want errDiffTooLarge", err)
+	}
+	if _, err := unifiedDiff("x", "y", strings.Repeat("a\n", maxDiffLines+1), "a\n", 3); err != errDiffTooLarge {
+		t.Errorf("unifiedDiff error = %v, want errDiffTooLarge", err)
+	}
+}
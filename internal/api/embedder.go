@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const embeddingTimeout = 30 * time.Second
+
+// Embedder 文本向量化
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embedderOf 获取知识库配置的向量化模型，测试时可以替换为本地实现
+var embedderOf = newKBEmbedder
+
+// openAIEmbedder 调用 OpenAI 兼容（包括 Ollama）或 Azure OpenAI 的 embeddings 接口
+type openAIEmbedder struct {
+	url    string
+	apiKey string
+	model  string
+	azure  bool
+	client *http.Client
+}
+
+// readEnv 读取知识库的 .env 文件，graphrag 使用其中的变量替换 settings.yaml 中的 ${VAR}
+func readEnv(kb string) map[string]string {
+	env := map[string]string{}
+	f, err := os.Open(kbPath(kb, ".env"))
+	if err != nil {
+		return env
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		env[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return env
+}
+
+// expandEnv 替换 ${VAR}，先查找 .env，再查找环境变量
+func expandEnv(s string, env map[string]string) string {
+	return os.Expand(s, func(key string) string {
+		if value, ok := env[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	})
+}
+
+// newKBEmbedder 由知识库 settings.yaml 的 embeddings.llm 创建向量化模型，未配置的字段使用 llm 的配置
+func newKBEmbedder(kb string) (Embedder, error) {
+	cfg, err := ReadSettings(kb)
+	if err != nil {
+		return nil, err
+	}
+	llm := LLMConfig{}
+	if cfg.Embeddings != nil && cfg.Embeddings.LLM != nil {
+		llm = *cfg.Embeddings.LLM
+	}
+	if cfg.LLM != nil {
+		if llm.APIKey == "" {
+			llm.APIKey = cfg.LLM.APIKey
+		}
+		if llm.APIBase == "" {
+			llm.APIBase = cfg.LLM.APIBase
+		}
+	}
+	if llm.Model == "" && llm.DeploymentName == "" {
+		return nil, fmt.Errorf("embeddings.llm.model is not set in settings")
+	}
+
+	env := readEnv(kb)
+	e := openAIEmbedder{
+		apiKey: expandEnv(llm.APIKey, env),
+		model:  llm.Model,
+		client: &http.Client{Timeout: embeddingTimeout},
+	}
+	base := strings.TrimRight(expandEnv(llm.APIBase, env), "/")
+	switch llm.Type {
+	case "", "openai_embedding":
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+		e.url = base + "/embeddings"
+	case "azure_openai_embedding":
+		if base == "" || llm.DeploymentName == "" || llm.APIVersion == "" {
+			return nil, fmt.Errorf("api_base, deployment_name and api_version are required for azure_openai_embedding")
+		}
+		e.azure = true
+		e.url = fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+			base, url.PathEscape(llm.DeploymentName), url.QueryEscape(llm.APIVersion))
+	default:
+		return nil, fmt.Errorf("embeddings.llm.type '%s' is not supported", llm.Type)
+	}
+	return &e, nil
+}
+
+// Embed 获取文本的向量
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	type EmbeddingReq struct {
+		Model string   `json:"model,omitempty"`
+		Input []string `json:"input"`
+	}
+	type EmbeddingRsp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	reqBody, err := json.Marshal(EmbeddingReq{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		if e.azure {
+			req.Header.Set("api-key", e.apiKey)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+e.apiKey)
+		}
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result EmbeddingRsp
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != nil {
+			return nil, fmt.Errorf("embedding request failed: %s", result.Error.Message)
+		}
+		return nil, fmt.Errorf("embedding request failed: status %d", resp.StatusCode)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors, expected %d", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, d := range result.Data {
+		index := d.Index
+		if index < 0 || index >= len(texts) {
+			index = i
+		}
+		vectors[index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,643 @@
+package api
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNeighborLimit = 50
+	maxNeighborLimit     = 1000
+	defaultSubgraphHops  = 2
+	maxSubgraphHops      = 5
+	defaultSubgraphNodes = 100
+	maxSubgraphNodes     = 2000
+	defaultSubgraphEdges = 500
+	maxSubgraphEdges     = 10000
+	maxPaths             = 10
+	defaultPathDepth     = 6
+)
+
+// 路径查找方式
+const (
+	PathShortest = "shortest" // 跳数最少
+	PathWeighted = "weighted" // 关系权重越大代价越小，代价为 1/weight
+)
+
+// GraphNode 图节点，即实体
+type GraphNode struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Degree      int    `json:"degree"`
+}
+
+// GraphEdge 图的边，即关系，graphrag 的关系无方向
+type GraphEdge struct {
+	ID          string  `json:"id"`
+	Source      string  `json:"source"`
+	Target      string  `json:"target"`
+	Description string  `json:"description"`
+	Weight      float64 `json:"weight"`
+}
+
+// Graph 由关系表构建的内存图，节点以 title 为键
+type Graph struct {
+	Nodes map[string]*GraphNode
+	Edges []GraphEdge
+	adj   map[string][]int // 节点关联的边在 Edges 中的下标
+	upper map[string]string
+}
+
+// graphCache 索引版本的图，输出表重新读取后重新构建
+type graphCache struct {
+	entities      *OutputTable
+	relationships *OutputTable
+	graph         *Graph
+}
+
+var (
+	graphs   = map[string]graphCache{}
+	graphMux sync.Mutex
+)
+
+func stringOf(value any) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// buildGraph 由关系表构建图，实体表可以为空，只用于补充节点的类型与描述
+func buildGraph(entities, relationships *OutputTable) *Graph {
+	g := Graph{
+		Nodes: map[string]*GraphNode{},
+		adj:   map[string][]int{},
+		upper: map[string]string{},
+	}
+	node := func(title string) *GraphNode {
+		n, ok := g.Nodes[title]
+		if !ok {
+			n = &GraphNode{Title: title}
+			g.Nodes[title] = n
+			g.upper[strings.ToUpper(title)] = title
+		}
+		return n
+	}
+
+	if entities != nil {
+		for _, row := range entities.Rows {
+			title := stringOf(row["title"])
+			if title == "" {
+				continue
+			}
+			n := node(title)
+			n.ID = stringOf(row["id"])
+			n.Type = stringOf(row["type"])
+			n.Description = stringOf(row["description"])
+		}
+	}
+
+	for _, row := range relationships.Rows {
+		source, target := stringOf(row["source"]), stringOf(row["target"])
+		if source == "" || target == "" {
+			continue
+		}
+		weight, ok := toFloat(row["weight"])
+		if !ok {
+			weight = 1
+		}
+		g.Edges = append(g.Edges, GraphEdge{
+			ID:          stringOf(row["id"]),
+			Source:      source,
+			Target:      target,
+			Description: stringOf(row["description"]),
+			Weight:      weight,
+		})
+		i := len(g.Edges) - 1
+		node(source).Degree++
+		g.adj[source] = append(g.adj[source], i)
+		if target != source {
+			node(target).Degree++
+			g.adj[target] = append(g.adj[target], i)
+		}
+	}
+	return &g
+}
+
+// ReadGraph 获取索引版本的图，关系表或实体表更新后重新构建
+func ReadGraph(kb, db string) (*Graph, error) {
+	relationships, err := ReadOutputTable(kb, db, TableRelationships)
+	if err != nil {
+		return nil, err
+	}
+	// 没有实体表时只使用关系表构建
+	entities, err := ReadOutputTable(kb, db, TableEntities)
+	if errors.Is(err, errTableNotFound) {
+		entities = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	key := kb + "/" + db
+	if db == "" {
+		key = kb + "/" + defaultDB
+	}
+
+	graphMux.Lock()
+	defer graphMux.Unlock()
+
+	if cache, ok := graphs[key]; ok && cache.relationships == relationships && cache.entities == entities {
+		return cache.graph, nil
+	}
+	g := buildGraph(entities, relationships)
+	graphs[key] = graphCache{entities: entities, relationships: relationships, graph: g}
+	return g, nil
+}
+
+// evictGraphs 删除匹配的索引版本的图
+func evictGraphs(m cacheMatcher) {
+	graphMux.Lock()
+	defer graphMux.Unlock()
+	for key := range graphs {
+		if m.matchKey(key) {
+			delete(graphs, key)
+		}
+	}
+}
+
+// Find 查找实体，先精确匹配 title，再忽略大小写匹配（graphrag 生成的 title 为大写）
+func (g *Graph) Find(entity string) (string, error) {
+	if _, ok := g.Nodes[entity]; ok {
+		return entity, nil
+	}
+	if title, ok := g.upper[strings.ToUpper(strings.TrimSpace(entity))]; ok {
+		return title, nil
+	}
+	return "", fmt.Errorf("entity '%s' not found", entity)
+}
+
+func (g *Graph) other(e GraphEdge, title string) string {
+	if e.Source == title {
+		return e.Target
+	}
+	return e.Source
+}
+
+// edgesByWeight 节点关联的边，按权重倒序
+func (g *Graph) edgesByWeight(title string) []int {
+	edges := slices.Clone(g.adj[title])
+	sort.SliceStable(edges, func(i, j int) bool { return g.Edges[edges[i]].Weight > g.Edges[edges[j]].Weight })
+	return edges
+}
+
+// GraphNeighbor 邻居节点与连接的边
+type GraphNeighbor struct {
+	Node *GraphNode `json:"node"`
+	Edge GraphEdge  `json:"edge"`
+}
+
+// Neighbors 获取节点的邻居，按关系权重倒序
+func (g *Graph) Neighbors(title string, limit int) ([]GraphNeighbor, int) {
+	edges := g.edgesByWeight(title)
+	neighbors := []GraphNeighbor{}
+	for _, i := range edges[:min(limit, len(edges))] {
+		e := g.Edges[i]
+		neighbors = append(neighbors, GraphNeighbor{Node: g.Nodes[g.other(e, title)], Edge: e})
+	}
+	return neighbors, len(edges)
+}
+
+// Subgraph 子图
+type Subgraph struct {
+	Nodes     []*GraphNode `json:"nodes"`
+	Edges     []GraphEdge  `json:"edges"`
+	Truncated bool         `json:"truncated"` // 是否因节点数或边数限制而截断
+}
+
+// Subgraph 获取从 roots 出发 hops 跳内的子图，同一跳内优先加入权重大的边连接的节点
+func (g *Graph) Subgraph(roots []string, hops, maxNodes, maxEdges int) *Subgraph {
+	sub := Subgraph{Nodes: []*GraphNode{}, Edges: []GraphEdge{}}
+	seen := map[string]bool{}
+	frontier := []string{}
+	for _, root := range roots {
+		if !seen[root] && len(sub.Nodes) < maxNodes {
+			seen[root] = true
+			sub.Nodes = append(sub.Nodes, g.Nodes[root])
+			frontier = append(frontier, root)
+		}
+	}
+
+	for hop := 0; hop < hops && len(frontier) > 0; hop++ {
+		next := []string{}
+		for _, title := range frontier {
+			for _, i := range g.edgesByWeight(title) {
+				other := g.other(g.Edges[i], title)
+				if seen[other] {
+					continue
+				}
+				if len(sub.Nodes) >= maxNodes {
+					sub.Truncated = true
+					break
+				}
+				seen[other] = true
+				sub.Nodes = append(sub.Nodes, g.Nodes[other])
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+
+	// 子图中节点之间的所有边，超出限制时保留权重大的边
+	edges := []int{}
+	for i, e := range g.Edges {
+		if seen[e.Source] && seen[e.Target] {
+			edges = append(edges, i)
+		}
+	}
+	if len(edges) > maxEdges {
+		sort.SliceStable(edges, func(i, j int) bool { return g.Edges[edges[i]].Weight > g.Edges[edges[j]].Weight })
+		edges = edges[:maxEdges]
+		sub.Truncated = true
+	}
+	sort.Ints(edges)
+	for _, i := range edges {
+		sub.Edges = append(sub.Edges, g.Edges[i])
+	}
+	return &sub
+}
+
+// GraphPath 两个节点之间的路径
+type GraphPath struct {
+	Nodes  []string    `json:"nodes"`
+	Edges  []GraphEdge `json:"edges"`
+	Cost   float64     `json:"cost"`
+	Weight float64     `json:"weight"` // 路径上关系权重之和
+}
+
+func edgeCost(mode string, e GraphEdge) float64 {
+	if mode == PathWeighted && e.Weight > 0 {
+		return 1 / e.Weight
+	}
+	return 1
+}
+
+type pathItem struct {
+	title string
+	cost  float64
+	hops  int
+}
+
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// pathState 搜索状态，同一节点以不同跳数到达时分别记录，
+// 否则代价更小但跳数更多的路径会挡住跳数限制内的路径
+type pathState struct {
+	title string
+	hops  int
+}
+
+// dijkstra 计算 source 到 target 代价最小的路径，跳过 removedNodes 与 removedEdges，路径最多 maxDepth 跳
+func (g *Graph) dijkstra(source, target, mode string, maxDepth int, removedNodes map[string]bool, removedEdges map[int]bool) (*GraphPath, []int) {
+	dist := map[pathState]float64{{title: source}: 0}
+	prev := map[pathState]int{}
+	q := &pathQueue{{title: source}}
+
+	// 边的代价为正，第一次取出 target 时即为代价最小的路径，且不含环
+	var end *pathState
+	for q.Len() > 0 {
+		item := heap.Pop(q).(pathItem)
+		state := pathState{title: item.title, hops: item.hops}
+		if item.cost > dist[state] {
+			continue
+		}
+		if item.title == target {
+			end = &state
+			break
+		}
+		if item.hops >= maxDepth {
+			continue
+		}
+		for _, i := range g.adj[item.title] {
+			if removedEdges[i] {
+				continue
+			}
+			e := g.Edges[i]
+			other := g.other(e, item.title)
+			if removedNodes[other] {
+				continue
+			}
+			next := pathState{title: other, hops: item.hops + 1}
+			cost := item.cost + edgeCost(mode, e)
+			if d, ok := dist[next]; ok && d <= cost {
+				continue
+			}
+			dist[next] = cost
+			prev[next] = i
+			heap.Push(q, pathItem{title: other, cost: cost, hops: next.hops})
+		}
+	}
+
+	if end == nil {
+		return nil, nil
+	}
+	edges := []int{}
+	for state := *end; state.hops > 0; {
+		i := prev[state]
+		edges = append(edges, i)
+		state = pathState{title: g.other(g.Edges[i], state.title), hops: state.hops - 1}
+	}
+	slices.Reverse(edges)
+	return g.pathOf(source, mode, edges), edges
+}
+
+// pathOf 由边的下标构造路径
+func (g *Graph) pathOf(source, mode string, edges []int) *GraphPath {
+	p := GraphPath{Nodes: []string{source}, Edges: []GraphEdge{}}
+	title := source
+	for _, i := range edges {
+		e := g.Edges[i]
+		title = g.other(e, title)
+		p.Nodes = append(p.Nodes, title)
+		p.Edges = append(p.Edges, e)
+		p.Cost += edgeCost(mode, e)
+		p.Weight += e.Weight
+	}
+	return &p
+}
+
+// Paths 使用 Yen 算法获取 source 到 target 代价最小的 k 条无环路径
+func (g *Graph) Paths(source, target, mode string, k, maxDepth int) []*GraphPath {
+	paths := []*GraphPath{}
+	if source == target {
+		return paths
+	}
+	first, firstEdges := g.dijkstra(source, target, mode, maxDepth, nil, nil)
+	if first == nil {
+		return paths
+	}
+	paths = append(paths, first)
+	found := [][]int{firstEdges}
+	candidates := []*GraphPath{}
+	candidateEdges := [][]int{}
+
+	for len(paths) < k {
+		last := found[len(found)-1]
+		lastPath := paths[len(paths)-1]
+		for i := range last {
+			spur := lastPath.Nodes[i]
+			root := last[:i]
+
+			// 移除与已找到路径共享相同前缀的下一条边，以及前缀上的节点
+			removedEdges := map[int]bool{}
+			for _, edges := range found {
+				if len(edges) > i && slices.Equal(edges[:i], root) {
+					removedEdges[edges[i]] = true
+				}
+			}
+			removedNodes := map[string]bool{}
+			for _, title := range lastPath.Nodes[:i] {
+				removedNodes[title] = true
+			}
+
+			spurPath, spurEdges := g.dijkstra(spur, target, mode, maxDepth-i, removedNodes, removedEdges)
+			if spurPath == nil {
+				continue
+			}
+			edges := append(slices.Clone(root), spurEdges...)
+			if slices.ContainsFunc(candidateEdges, func(c []int) bool { return slices.Equal(c, edges) }) {
+				continue
+			}
+			candidates = append(candidates, g.pathOf(source, mode, edges))
+			candidateEdges = append(candidateEdges, edges)
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		best := 0
+		for i := range candidates {
+			if candidates[i].Cost < candidates[best].Cost {
+				best = i
+			}
+		}
+		paths = append(paths, candidates[best])
+		found = append(found, candidateEdges[best])
+		candidates = slices.Delete(candidates, best, best+1)
+		candidateEdges = slices.Delete(candidateEdges, best, best+1)
+	}
+	return paths
+}
+
+type GraphApi struct {
+}
+
+func (ga *GraphApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/graph")
+
+	r.POST("/neighbors", ga.GetNeighbors)
+	r.POST("/subgraph", ga.GetSubgraph)
+	r.POST("/paths", ga.GetPaths)
+	r.POST("/export", ga.ExportGraph)
+	r.POST("/search", ga.SearchEntities)
+	r.POST("/semantic_search", ga.SemanticSearch)
+	r.POST("/stats", ga.GetStats)
+}
+
+// limitOf 返回默认值或不超过最大值的限制
+func limitOf(value, def, max int) int {
+	if value <= 0 {
+		return def
+	}
+	return min(value, max)
+}
+
+// GetNeighbors 获取实体的邻居
+func (ga *GraphApi) GetNeighbors(c *gin.Context) {
+	type GetNeighborsReq struct {
+		KB     string `json:"kb"`
+		DB     string `json:"db"`
+		Entity string `json:"entity"`
+		Limit  int    `json:"limit"`
+	}
+	type GetNeighborsRsp struct {
+		BaseRsp
+		Node      *GraphNode      `json:"node"`
+		Total     int             `json:"total"`
+		Neighbors []GraphNeighbor `json:"neighbors"`
+	}
+
+	req := GetNeighborsReq{}
+	rsp := GetNeighborsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	g, err := ReadGraph(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	title, err := g.Find(req.Entity)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Node = g.Nodes[title]
+	rsp.Neighbors, rsp.Total = g.Neighbors(title, limitOf(req.Limit, defaultNeighborLimit, maxNeighborLimit))
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetSubgraph 获取实体 k 跳内的子图
+func (ga *GraphApi) GetSubgraph(c *gin.Context) {
+	type GetSubgraphReq struct {
+		KB       string   `json:"kb"`
+		DB       string   `json:"db"`
+		Entities []string `json:"entities"`
+		Hops     int      `json:"hops"`
+		MaxNodes int      `json:"max_nodes"`
+		MaxEdges int      `json:"max_edges"`
+	}
+	type GetSubgraphRsp struct {
+		BaseRsp
+		*Subgraph
+	}
+
+	req := GetSubgraphReq{}
+	rsp := GetSubgraphRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if len(req.Entities) == 0 {
+		rsp.Code = -1
+		rsp.Msg = "entities is empty"
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	g, err := ReadGraph(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	roots := []string{}
+	for _, entity := range req.Entities {
+		title, err := g.Find(entity)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusNotFound, rsp)
+			return
+		}
+		roots = append(roots, title)
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Subgraph = g.Subgraph(roots,
+		limitOf(req.Hops, defaultSubgraphHops, maxSubgraphHops),
+		limitOf(req.MaxNodes, defaultSubgraphNodes, maxSubgraphNodes),
+		limitOf(req.MaxEdges, defaultSubgraphEdges, maxSubgraphEdges))
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetPaths 获取两个实体之间的最短路径或权重最大的 k 条路径
+func (ga *GraphApi) GetPaths(c *gin.Context) {
+	type GetPathsReq struct {
+		KB       string `json:"kb"`
+		DB       string `json:"db"`
+		Source   string `json:"source"`
+		Target   string `json:"target"`
+		Mode     string `json:"mode"` // shortest 或 weighted，默认 shortest
+		K        int    `json:"k"`
+		MaxDepth int    `json:"max_depth"`
+	}
+	type GetPathsRsp struct {
+		BaseRsp
+		Paths []*GraphPath `json:"paths"`
+	}
+
+	req := GetPathsReq{}
+	rsp := GetPathsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = PathShortest
+	}
+	if req.Mode != PathShortest && req.Mode != PathWeighted {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("mode '%s' is invalid, must be %s or %s", req.Mode, PathShortest, PathWeighted)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	g, err := ReadGraph(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	source, err := g.Find(req.Source)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	target, err := g.Find(req.Target)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Paths = g.Paths(source, target, req.Mode,
+		limitOf(req.K, 1, maxPaths), limitOf(req.MaxDepth, defaultPathDepth, maxSubgraphHops*2))
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 图导出格式
+const (
+	ExportGraphML = "graphml"
+	ExportGEXF    = "gexf"
+	ExportJSON    = "json"   // networkx 的 node-link 格式
+	ExportCSV     = "csv"    // zip 中包含 nodes.csv 与 edges.csv
+	ExportCypher  = "cypher" // Neo4j 导入脚本
+)
+
+var exportFormats = map[string]struct {
+	ext         string
+	contentType string
+}{
+	ExportGraphML: {".graphml", "application/xml"},
+	ExportGEXF:    {".gexf", "application/xml"},
+	ExportJSON:    {".json", "application/json"},
+	ExportCSV:     {".zip", "application/zip"},
+	ExportCypher:  {".cypher", "text/plain; charset=utf-8"},
+}
+
+// ExportFilter 导出过滤条件，多个条件同时满足
+type ExportFilter struct {
+	Community string   `json:"community"` // 社区 ID，包含各层级
+	Types     []string `json:"types"`     // 实体类型
+	Entities  []string `json:"entities"`  // 子图的起点实体
+	Hops      int      `json:"hops"`
+	MaxNodes  int      `json:"max_nodes"`
+	MaxEdges  int      `json:"max_edges"`
+}
+
+// ExportGraph 导出的图，Communities 为实体所属的社区 ID
+type ExportGraph struct {
+	Nodes       []*GraphNode
+	Edges       []GraphEdge
+	Communities map[string][]string
+}
+
+// entityCommunities 获取实体所属的社区 ID，key 为实体 title
+func entityCommunities(kb, db string) (map[string][]string, error) {
+	communities := map[string][]string{}
+	if _, err := tableFile(kb, db, TableCommunities); err != nil {
+		// 没有社区表时（如跳过了社区检测）不导出社区
+		return communities, nil
+	}
+	cs, err := ReadCommunities(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	for title, list := range cs.byTitle {
+		for _, c := range list {
+			communities[title] = append(communities[title], c.ID)
+		}
+	}
+	return communities, nil
+}
+
+// numberOf 数字字符串转为数值以便按数值排序
+func numberOf(s string) any {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// ReadExportGraph 获取过滤后的图
+func ReadExportGraph(kb, db string, filter ExportFilter) (*ExportGraph, error) {
+	g, err := ReadGraph(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	communities, err := entityCommunities(kb, db)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*GraphNode{}
+	if len(filter.Entities) > 0 {
+		roots := []string{}
+		for _, entity := range filter.Entities {
+			title, err := g.Find(entity)
+			if err != nil {
+				return nil, err
+			}
+			roots = append(roots, title)
+		}
+		nodes = g.Subgraph(roots,
+			limitOf(filter.Hops, defaultSubgraphHops, maxSubgraphHops),
+			limitOf(filter.MaxNodes, defaultSubgraphNodes, maxSubgraphNodes),
+			maxSubgraphEdges).Nodes
+	} else {
+		for _, node := range g.Nodes {
+			nodes = append(nodes, node)
+		}
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Title < nodes[j].Title })
+	}
+
+	eg := ExportGraph{Nodes: []*GraphNode{}, Edges: []GraphEdge{}, Communities: map[string][]string{}}
+	included := map[string]bool{}
+	for _, node := range nodes {
+		if filter.Community != "" && !slices.Contains(communities[node.Title], filter.Community) {
+			continue
+		}
+		if len(filter.Types) > 0 && !slices.ContainsFunc(filter.Types, func(t string) bool { return strings.EqualFold(t, node.Type) }) {
+			continue
+		}
+		included[node.Title] = true
+		eg.Nodes = append(eg.Nodes, node)
+		if ids, ok := communities[node.Title]; ok {
+			eg.Communities[node.Title] = ids
+		}
+	}
+
+	for _, e := range g.Edges {
+		if included[e.Source] && included[e.Target] {
+			eg.Edges = append(eg.Edges, e)
+		}
+	}
+	if len(filter.Entities) > 0 {
+		maxEdges := limitOf(filter.MaxEdges, defaultSubgraphEdges, maxSubgraphEdges)
+		if len(eg.Edges) > maxEdges {
+			sort.SliceStable(eg.Edges, func(i, j int) bool { return eg.Edges[i].Weight > eg.Edges[j].Weight })
+			eg.Edges = eg.Edges[:maxEdges]
+		}
+	}
+	return &eg, nil
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func formatWeight(w float64) string {
+	return strconv.FormatFloat(w, 'g', -1, 64)
+}
+
+// WriteGraphML 导出为 GraphML
+func (eg *ExportGraph) WriteGraphML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, xml.Header)
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="type" for="node" attr.name="type" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="description" for="node" attr.name="description" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="degree" for="node" attr.name="degree" attr.type="int"/>`)
+	fmt.Fprintln(bw, `  <key id="communities" for="node" attr.name="communities" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>`)
+	fmt.Fprintln(bw, `  <key id="edge_description" for="edge" attr.name="description" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <graph id="G" edgedefault="undirected">`)
+	for _, n := range eg.Nodes {
+		fmt.Fprintf(bw, "    <node id=\"%s\">\n", xmlEscape(n.Title))
+		fmt.Fprintf(bw, "      <data key=\"type\">%s</data>\n", xmlEscape(n.Type))
+		fmt.Fprintf(bw, "      <data key=\"description\">%s</data>\n", xmlEscape(n.Description))
+		fmt.Fprintf(bw, "      <data key=\"degree\">%d</data>\n", n.Degree)
+		fmt.Fprintf(bw, "      <data key=\"communities\">%s</data>\n", xmlEscape(strings.Join(eg.Communities[n.Title], ",")))
+		fmt.Fprintln(bw, "    </node>")
+	}
+	for i, e := range eg.Edges {
+		fmt.Fprintf(bw, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, xmlEscape(e.Source), xmlEscape(e.Target))
+		fmt.Fprintf(bw, "      <data key=\"weight\">%s</data>\n", formatWeight(e.Weight))
+		fmt.Fprintf(bw, "      <data key=\"edge_description\">%s</data>\n", xmlEscape(e.Description))
+		fmt.Fprintln(bw, "    </edge>")
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// WriteGEXF 导出为 GEXF 1.3，Gephi 可以直接打开
+func (eg *ExportGraph) WriteGEXF(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, xml.Header)
+	fmt.Fprintln(bw, `<gexf xmlns="http://gexf.net/1.3" version="1.3">`)
+	fmt.Fprintln(bw, `  <graph mode="static" defaultedgetype="undirected">`)
+	fmt.Fprintln(bw, `    <attributes class="node">`)
+	fmt.Fprintln(bw, `      <attribute id="0" title="type" type="string"/>`)
+	fmt.Fprintln(bw, `      <attribute id="1" title="description" type="string"/>`)
+	fmt.Fprintln(bw, `      <attribute id="2" title="degree" type="integer"/>`)
+	fmt.Fprintln(bw, `      <attribute id="3" title="communities" type="string"/>`)
+	fmt.Fprintln(bw, `    </attributes>`)
+	fmt.Fprintln(bw, `    <attributes class="edge">`)
+	fmt.Fprintln(bw, `      <attribute id="0" title="description" type="string"/>`)
+	fmt.Fprintln(bw, `    </attributes>`)
+	fmt.Fprintln(bw, `    <nodes>`)
+	for _, n := range eg.Nodes {
+		fmt.Fprintf(bw, "      <node id=\"%s\" label=\"%s\">\n", xmlEscape(n.Title), xmlEscape(n.Title))
+		fmt.Fprintln(bw, "        <attvalues>")
+		fmt.Fprintf(bw, "          <attvalue for=\"0\" value=\"%s\"/>\n", xmlEscape(n.Type))
+		fmt.Fprintf(bw, "          <attvalue for=\"1\" value=\"%s\"/>\n", xmlEscape(n.Description))
+		fmt.Fprintf(bw, "          <attvalue for=\"2\" value=\"%d\"/>\n", n.Degree)
+		fmt.Fprintf(bw, "          <attvalue for=\"3\" value=\"%s\"/>\n", xmlEscape(strings.Join(eg.Communities[n.Title], ",")))
+		fmt.Fprintln(bw, "        </attvalues>")
+		fmt.Fprintln(bw, "      </node>")
+	}
+	fmt.Fprintln(bw, `    </nodes>`)
+	fmt.Fprintln(bw, `    <edges>`)
+	for i, e := range eg.Edges {
+		fmt.Fprintf(bw, "      <edge id=\"%d\" source=\"%s\" target=\"%s\" weight=\"%s\">\n",
+			i, xmlEscape(e.Source), xmlEscape(e.Target), formatWeight(e.Weight))
+		fmt.Fprintf(bw, "        <attvalues><attvalue for=\"0\" value=\"%s\"/></attvalues>\n", xmlEscape(e.Description))
+		fmt.Fprintln(bw, "      </edge>")
+	}
+	fmt.Fprintln(bw, `    </edges>`)
+	fmt.Fprintln(bw, `  </graph>`)
+	fmt.Fprintln(bw, `</gexf>`)
+	return bw.Flush()
+}
+
+// WriteJSON 导出为 networkx 的 node-link JSON
+func (eg *ExportGraph) WriteJSON(w io.Writer) error {
+	type node struct {
+		ID          string   `json:"id"`
+		EntityID    string   `json:"entity_id"`
+		Type        string   `json:"type"`
+		Description string   `json:"description"`
+		Degree      int      `json:"degree"`
+		Communities []string `json:"communities"`
+	}
+	type link struct {
+		ID          string  `json:"id"`
+		Source      string  `json:"source"`
+		Target      string  `json:"target"`
+		Weight      float64 `json:"weight"`
+		Description string  `json:"description"`
+	}
+	data := struct {
+		Directed   bool     `json:"directed"`
+		Multigraph bool     `json:"multigraph"`
+		Graph      struct{} `json:"graph"`
+		Nodes      []node   `json:"nodes"`
+		Links      []link   `json:"links"`
+	}{Nodes: []node{}, Links: []link{}}
+	for _, n := range eg.Nodes {
+		communities := eg.Communities[n.Title]
+		if communities == nil {
+			communities = []string{}
+		}
+		data.Nodes = append(data.Nodes, node{n.Title, n.ID, n.Type, n.Description, n.Degree, communities})
+	}
+	for _, e := range eg.Edges {
+		data.Links = append(data.Links, link{e.ID, e.Source, e.Target, e.Weight, e.Description})
+	}
+	return json.NewEncoder(w).Encode(data)
+}
+
+// WriteCSV 导出为 zip，包含 nodes.csv 与 edges.csv
+func (eg *ExportGraph) WriteCSV(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create("nodes.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"id", "title", "type", "description", "degree", "communities"})
+	for _, n := range eg.Nodes {
+		cw.Write([]string{n.ID, n.Title, n.Type, n.Description, strconv.Itoa(n.Degree), strings.Join(eg.Communities[n.Title], ",")})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	f, err = zw.Create("edges.csv")
+	if err != nil {
+		return err
+	}
+	cw = csv.NewWriter(f)
+	cw.Write([]string{"id", "source", "target", "description", "weight"})
+	for _, e := range eg.Edges {
+		cw.Write([]string{e.ID, e.Source, e.Target, e.Description, formatWeight(e.Weight)})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// cypherString 转为 Cypher 字符串字面量
+func cypherString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// WriteCypher 导出为 Neo4j 导入脚本，实体为 :Entity 节点，关系为 :RELATED 边
+func (eg *ExportGraph) WriteCypher(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "CREATE CONSTRAINT entity_title IF NOT EXISTS FOR (n:Entity) REQUIRE n.title IS UNIQUE;")
+	for _, n := range eg.Nodes {
+		communities := []string{}
+		for _, id := range eg.Communities[n.Title] {
+			communities = append(communities, cypherString(id))
+		}
+		fmt.Fprintf(bw, "MERGE (n:Entity {title: %s}) SET n.id = %s, n.type = %s, n.description = %s, n.degree = %d, n.communities = [%s];\n",
+			cypherString(n.Title), cypherString(n.ID), cypherString(n.Type), cypherString(n.Description), n.Degree, strings.Join(communities, ", "))
+	}
+	for _, e := range eg.Edges {
+		fmt.Fprintf(bw, "MATCH (a:Entity {title: %s}), (b:Entity {title: %s}) MERGE (a)-[r:RELATED {id: %s}]->(b) SET r.weight = %s, r.description = %s;\n",
+			cypherString(e.Source), cypherString(e.Target), cypherString(e.ID), formatWeight(e.Weight), cypherString(e.Description))
+	}
+	return bw.Flush()
+}
+
+// Write 按格式导出
+func (eg *ExportGraph) Write(w io.Writer, format string) error {
+	switch format {
+	case ExportGraphML:
+		return eg.WriteGraphML(w)
+	case ExportGEXF:
+		return eg.WriteGEXF(w)
+	case ExportJSON:
+		return eg.WriteJSON(w)
+	case ExportCSV:
+		return eg.WriteCSV(w)
+	case ExportCypher:
+		return eg.WriteCypher(w)
+	}
+	return fmt.Errorf("format '%s' is invalid", format)
+}
+
+// ExportGraph 导出知识图谱，可按社区、实体类型或子图过滤
+func (ga *GraphApi) ExportGraph(c *gin.Context) {
+	type ExportGraphReq struct {
+		KB     string `json:"kb"`
+		DB     string `json:"db"`
+		Format string `json:"format"`
+		ExportFilter
+	}
+	type ExportGraphRsp struct {
+		BaseRsp
+	}
+
+	req := ExportGraphReq{}
+	rsp := ExportGraphRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	format, ok := exportFormats[req.Format]
+	if !ok {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("format '%s' is invalid, must be one of graphml, gexf, json, csv, cypher", req.Format)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	eg, err := ReadExportGraph(req.KB, req.DB, req.ExportFilter)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	db := req.DB
+	if db == "" {
+		db = defaultDB
+	}
+	c.Header("Content-Type", format.contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": req.KB + "-" + db + format.ext}))
+	c.Status(http.StatusOK)
+	// 已开始写入响应，出错时只能中断
+	if err := eg.Write(c.Writer, req.Format); err != nil {
+		c.Error(err)
+	}
+}
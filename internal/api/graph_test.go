@@ -0,0 +1,66 @@
+package api
+
+import (
+	"slices"
+	"testing"
+)
+
+// testGraph 由 (source, target, weight) 构建图
+func testGraph(edges ...[3]any) *Graph {
+	relationships := OutputTable{Columns: []string{"source", "target", "weight"}}
+	for _, e := range edges {
+		relationships.Rows = append(relationships.Rows, map[string]any{"source": e[0], "target": e[1], "weight": e[2]})
+	}
+	return buildGraph(nil, &relationships)
+}
+
+func TestPaths(t *testing.T) {
+	// S-A-B 权重大代价小，但经过 A 到达 B 已用 2 跳，max_depth 为 2 时只能走 S-B-T
+	g := testGraph(
+		[3]any{"S", "A", 100.0},
+		[3]any{"A", "B", 100.0},
+		[3]any{"S", "B", 1.0},
+		[3]any{"B", "T", 1.0},
+	)
+
+	tests := []struct {
+		name     string
+		mode     string
+		k        int
+		maxDepth int
+		want     [][]string
+	}{
+		{"weighted within depth", PathWeighted, 1, 2, [][]string{{"S", "B", "T"}}},
+		{"weighted", PathWeighted, 1, 3, [][]string{{"S", "A", "B", "T"}}},
+		{"weighted k paths", PathWeighted, 3, 3, [][]string{{"S", "A", "B", "T"}, {"S", "B", "T"}}},
+		{"weighted k paths within depth", PathWeighted, 3, 2, [][]string{{"S", "B", "T"}}},
+		{"shortest", PathShortest, 2, 3, [][]string{{"S", "B", "T"}, {"S", "A", "B", "T"}}},
+		{"too deep", PathShortest, 1, 1, [][]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := g.Paths("S", "T", tt.mode, tt.k, tt.maxDepth)
+			got := [][]string{}
+			for _, p := range paths {
+				got = append(got, p.Nodes)
+				if len(p.Edges) != len(p.Nodes)-1 {
+					t.Errorf("path %v has %d edges", p.Nodes, len(p.Edges))
+				}
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathsCost(t *testing.T) {
+	g := testGraph([3]any{"S", "A", 4.0}, [3]any{"A", "T", 2.0})
+	paths := g.Paths("S", "T", PathWeighted, 1, 2)
+	if len(paths) != 1 {
+		t.Fatalf("got %d paths, want 1", len(paths))
+	}
+	if p := paths[0]; p.Cost != 0.75 || p.Weight != 6 {
+		t.Errorf("cost = %v, weight = %v, want 0.75 and 6", p.Cost, p.Weight)
+	}
+}
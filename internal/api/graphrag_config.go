@@ -0,0 +1,503 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GraphRAGConfig GraphRAG 0.5 settings.yaml 配置
+// 参考 https://microsoft.github.io/graphrag/config/yaml/
+type GraphRAGConfig struct {
+	EncodingModel         string                       `yaml:"encoding_model,omitempty" json:"encoding_model,omitempty"`
+	RootDir               string                       `yaml:"root_dir,omitempty" json:"root_dir,omitempty"`
+	SkipWorkflows         []string                     `yaml:"skip_workflows,omitempty" json:"skip_workflows,omitempty"`
+	AsyncMode             string                       `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM                   *LLMConfig                   `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization       *ParallelizationConfig       `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	Embeddings            *EmbeddingsConfig            `yaml:"embeddings,omitempty" json:"embeddings,omitempty"`
+	Input                 *InputConfig                 `yaml:"input,omitempty" json:"input,omitempty"`
+	Chunks                *ChunksConfig                `yaml:"chunks,omitempty" json:"chunks,omitempty"`
+	Cache                 *StorageConfig               `yaml:"cache,omitempty" json:"cache,omitempty"`
+	Reporting             *StorageConfig               `yaml:"reporting,omitempty" json:"reporting,omitempty"`
+	Storage               *StorageConfig               `yaml:"storage,omitempty" json:"storage,omitempty"`
+	UpdateIndexStorage    *StorageConfig               `yaml:"update_index_storage,omitempty" json:"update_index_storage,omitempty"`
+	EntityExtraction      *EntityExtractionConfig      `yaml:"entity_extraction,omitempty" json:"entity_extraction,omitempty"`
+	SummarizeDescriptions *SummarizeDescriptionsConfig `yaml:"summarize_descriptions,omitempty" json:"summarize_descriptions,omitempty"`
+	ClaimExtraction       *ClaimExtractionConfig       `yaml:"claim_extraction,omitempty" json:"claim_extraction,omitempty"`
+	CommunityReports      *CommunityReportsConfig      `yaml:"community_reports,omitempty" json:"community_reports,omitempty"`
+	ClusterGraph          *ClusterGraphConfig          `yaml:"cluster_graph,omitempty" json:"cluster_graph,omitempty"`
+	EmbedGraph            *EmbedGraphConfig            `yaml:"embed_graph,omitempty" json:"embed_graph,omitempty"`
+	UMAP                  *UMAPConfig                  `yaml:"umap,omitempty" json:"umap,omitempty"`
+	Snapshots             *SnapshotsConfig             `yaml:"snapshots,omitempty" json:"snapshots,omitempty"`
+	LocalSearch           *LocalSearchConfig           `yaml:"local_search,omitempty" json:"local_search,omitempty"`
+	GlobalSearch          *GlobalSearchConfig          `yaml:"global_search,omitempty" json:"global_search,omitempty"`
+	DriftSearch           *DriftSearchConfig           `yaml:"drift_search,omitempty" json:"drift_search,omitempty"`
+}
+
+// LLMConfig 大模型参数
+type LLMConfig struct {
+	APIKey                         string   `yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	Type                           string   `yaml:"type,omitempty" json:"type,omitempty"`
+	Model                          string   `yaml:"model,omitempty" json:"model,omitempty"`
+	MaxTokens                      *int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	Temperature                    *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP                           *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	N                              *int     `yaml:"n,omitempty" json:"n,omitempty"`
+	FrequencyPenalty               *float64 `yaml:"frequency_penalty,omitempty" json:"frequency_penalty,omitempty"`
+	PresencePenalty                *float64 `yaml:"presence_penalty,omitempty" json:"presence_penalty,omitempty"`
+	RequestTimeout                 *float64 `yaml:"request_timeout,omitempty" json:"request_timeout,omitempty"`
+	APIBase                        string   `yaml:"api_base,omitempty" json:"api_base,omitempty"`
+	APIVersion                     string   `yaml:"api_version,omitempty" json:"api_version,omitempty"`
+	Organization                   string   `yaml:"organization,omitempty" json:"organization,omitempty"`
+	Proxy                          string   `yaml:"proxy,omitempty" json:"proxy,omitempty"`
+	Audience                       string   `yaml:"audience,omitempty" json:"audience,omitempty"`
+	DeploymentName                 string   `yaml:"deployment_name,omitempty" json:"deployment_name,omitempty"`
+	ModelSupportsJSON              *bool    `yaml:"model_supports_json,omitempty" json:"model_supports_json,omitempty"`
+	TokensPerMinute                *int     `yaml:"tokens_per_minute,omitempty" json:"tokens_per_minute,omitempty"`
+	RequestsPerMinute              *int     `yaml:"requests_per_minute,omitempty" json:"requests_per_minute,omitempty"`
+	MaxRetries                     *int     `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	MaxRetryWait                   *float64 `yaml:"max_retry_wait,omitempty" json:"max_retry_wait,omitempty"`
+	SleepOnRateLimitRecommendation *bool    `yaml:"sleep_on_rate_limit_recommendation,omitempty" json:"sleep_on_rate_limit_recommendation,omitempty"`
+	ConcurrentRequests             *int     `yaml:"concurrent_requests,omitempty" json:"concurrent_requests,omitempty"`
+}
+
+// ParallelizationConfig 并发参数
+type ParallelizationConfig struct {
+	Stagger    *float64 `yaml:"stagger,omitempty" json:"stagger,omitempty"`
+	NumThreads *int     `yaml:"num_threads,omitempty" json:"num_threads,omitempty"`
+}
+
+// EmbeddingsConfig 文本向量化参数
+type EmbeddingsConfig struct {
+	AsyncMode       string                 `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM             *LLMConfig             `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization *ParallelizationConfig `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	BatchSize       *int                   `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+	BatchMaxTokens  *int                   `yaml:"batch_max_tokens,omitempty" json:"batch_max_tokens,omitempty"`
+	Target          string                 `yaml:"target,omitempty" json:"target,omitempty"`
+	Skip            []string               `yaml:"skip,omitempty" json:"skip,omitempty"`
+	VectorStore     map[string]any         `yaml:"vector_store,omitempty" json:"vector_store,omitempty"`
+	Strategy        map[string]any         `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// InputConfig 输入参数
+type InputConfig struct {
+	Type                     string         `yaml:"type,omitempty" json:"type,omitempty"`
+	FileType                 string         `yaml:"file_type,omitempty" json:"file_type,omitempty"`
+	BaseDir                  string         `yaml:"base_dir,omitempty" json:"base_dir,omitempty"`
+	ConnectionString         string         `yaml:"connection_string,omitempty" json:"connection_string,omitempty"`
+	StorageAccountBlobURL    string         `yaml:"storage_account_blob_url,omitempty" json:"storage_account_blob_url,omitempty"`
+	ContainerName            string         `yaml:"container_name,omitempty" json:"container_name,omitempty"`
+	FileEncoding             string         `yaml:"file_encoding,omitempty" json:"file_encoding,omitempty"`
+	FilePattern              string         `yaml:"file_pattern,omitempty" json:"file_pattern,omitempty"`
+	FileFilter               map[string]any `yaml:"file_filter,omitempty" json:"file_filter,omitempty"`
+	SourceColumn             string         `yaml:"source_column,omitempty" json:"source_column,omitempty"`
+	TimestampColumn          string         `yaml:"timestamp_column,omitempty" json:"timestamp_column,omitempty"`
+	TimestampFormat          string         `yaml:"timestamp_format,omitempty" json:"timestamp_format,omitempty"`
+	TextColumn               string         `yaml:"text_column,omitempty" json:"text_column,omitempty"`
+	TitleColumn              string         `yaml:"title_column,omitempty" json:"title_column,omitempty"`
+	DocumentAttributeColumns []string       `yaml:"document_attribute_columns,omitempty" json:"document_attribute_columns,omitempty"`
+}
+
+// ChunksConfig 分块参数
+type ChunksConfig struct {
+	Size           *int           `yaml:"size,omitempty" json:"size,omitempty"`
+	Overlap        *int           `yaml:"overlap,omitempty" json:"overlap,omitempty"`
+	GroupByColumns []string       `yaml:"group_by_columns,omitempty" json:"group_by_columns,omitempty"`
+	EncodingModel  string         `yaml:"encoding_model,omitempty" json:"encoding_model,omitempty"`
+	Strategy       map[string]any `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// StorageConfig cache、reporting、storage 等存储参数
+type StorageConfig struct {
+	Type                  string `yaml:"type,omitempty" json:"type,omitempty"`
+	BaseDir               string `yaml:"base_dir,omitempty" json:"base_dir,omitempty"`
+	ConnectionString      string `yaml:"connection_string,omitempty" json:"connection_string,omitempty"`
+	ContainerName         string `yaml:"container_name,omitempty" json:"container_name,omitempty"`
+	StorageAccountBlobURL string `yaml:"storage_account_blob_url,omitempty" json:"storage_account_blob_url,omitempty"`
+	CosmosDBAccountURL    string `yaml:"cosmosdb_account_url,omitempty" json:"cosmosdb_account_url,omitempty"`
+}
+
+// EntityExtractionConfig 实体抽取参数
+type EntityExtractionConfig struct {
+	Prompt          string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	EntityTypes     []string               `yaml:"entity_types,omitempty" json:"entity_types,omitempty"`
+	MaxGleanings    *int                   `yaml:"max_gleanings,omitempty" json:"max_gleanings,omitempty"`
+	EncodingModel   string                 `yaml:"encoding_model,omitempty" json:"encoding_model,omitempty"`
+	AsyncMode       string                 `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM             *LLMConfig             `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization *ParallelizationConfig `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	Strategy        map[string]any         `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// SummarizeDescriptionsConfig 描述摘要参数
+type SummarizeDescriptionsConfig struct {
+	Prompt          string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	MaxLength       *int                   `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	AsyncMode       string                 `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM             *LLMConfig             `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization *ParallelizationConfig `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	Strategy        map[string]any         `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// ClaimExtractionConfig 声明抽取参数
+type ClaimExtractionConfig struct {
+	Enabled         *bool                  `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Prompt          string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Description     string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	MaxGleanings    *int                   `yaml:"max_gleanings,omitempty" json:"max_gleanings,omitempty"`
+	EncodingModel   string                 `yaml:"encoding_model,omitempty" json:"encoding_model,omitempty"`
+	AsyncMode       string                 `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM             *LLMConfig             `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization *ParallelizationConfig `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	Strategy        map[string]any         `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// CommunityReportsConfig 社区报告参数
+type CommunityReportsConfig struct {
+	Prompt          string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	MaxLength       *int                   `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	MaxInputLength  *int                   `yaml:"max_input_length,omitempty" json:"max_input_length,omitempty"`
+	AsyncMode       string                 `yaml:"async_mode,omitempty" json:"async_mode,omitempty"`
+	LLM             *LLMConfig             `yaml:"llm,omitempty" json:"llm,omitempty"`
+	Parallelization *ParallelizationConfig `yaml:"parallelization,omitempty" json:"parallelization,omitempty"`
+	Strategy        map[string]any         `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// ClusterGraphConfig 图聚类参数
+type ClusterGraphConfig struct {
+	MaxClusterSize *int           `yaml:"max_cluster_size,omitempty" json:"max_cluster_size,omitempty"`
+	Strategy       map[string]any `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// EmbedGraphConfig node2vec 图嵌入参数
+type EmbedGraphConfig struct {
+	Enabled    *bool          `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	NumWalks   *int           `yaml:"num_walks,omitempty" json:"num_walks,omitempty"`
+	WalkLength *int           `yaml:"walk_length,omitempty" json:"walk_length,omitempty"`
+	WindowSize *int           `yaml:"window_size,omitempty" json:"window_size,omitempty"`
+	Iterations *int           `yaml:"iterations,omitempty" json:"iterations,omitempty"`
+	RandomSeed *int           `yaml:"random_seed,omitempty" json:"random_seed,omitempty"`
+	Strategy   map[string]any `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+}
+
+// UMAPConfig UMAP 参数
+type UMAPConfig struct {
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+}
+
+// SnapshotsConfig 快照参数
+type SnapshotsConfig struct {
+	Embeddings    *bool `yaml:"embeddings,omitempty" json:"embeddings,omitempty"`
+	GraphML       *bool `yaml:"graphml,omitempty" json:"graphml,omitempty"`
+	RawEntities   *bool `yaml:"raw_entities,omitempty" json:"raw_entities,omitempty"`
+	TopLevelNodes *bool `yaml:"top_level_nodes,omitempty" json:"top_level_nodes,omitempty"`
+	Transient     *bool `yaml:"transient,omitempty" json:"transient,omitempty"`
+}
+
+// LocalSearchConfig Local 查询参数
+type LocalSearchConfig struct {
+	Prompt                      string   `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	TextUnitProp                *float64 `yaml:"text_unit_prop,omitempty" json:"text_unit_prop,omitempty"`
+	CommunityProp               *float64 `yaml:"community_prop,omitempty" json:"community_prop,omitempty"`
+	ConversationHistoryMaxTurns *int     `yaml:"conversation_history_max_turns,omitempty" json:"conversation_history_max_turns,omitempty"`
+	TopKEntities                *int     `yaml:"top_k_entities,omitempty" json:"top_k_entities,omitempty"`
+	TopKRelationships           *int     `yaml:"top_k_relationships,omitempty" json:"top_k_relationships,omitempty"`
+	Temperature                 *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP                        *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	N                           *int     `yaml:"n,omitempty" json:"n,omitempty"`
+	MaxTokens                   *int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	LLMMaxTokens                *int     `yaml:"llm_max_tokens,omitempty" json:"llm_max_tokens,omitempty"`
+}
+
+// GlobalSearchConfig Global 查询参数
+type GlobalSearchConfig struct {
+	MapPrompt                         string   `yaml:"map_prompt,omitempty" json:"map_prompt,omitempty"`
+	ReducePrompt                      string   `yaml:"reduce_prompt,omitempty" json:"reduce_prompt,omitempty"`
+	KnowledgePrompt                   string   `yaml:"knowledge_prompt,omitempty" json:"knowledge_prompt,omitempty"`
+	Temperature                       *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP                              *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	N                                 *int     `yaml:"n,omitempty" json:"n,omitempty"`
+	MaxTokens                         *int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	DataMaxTokens                     *int     `yaml:"data_max_tokens,omitempty" json:"data_max_tokens,omitempty"`
+	MapMaxTokens                      *int     `yaml:"map_max_tokens,omitempty" json:"map_max_tokens,omitempty"`
+	ReduceMaxTokens                   *int     `yaml:"reduce_max_tokens,omitempty" json:"reduce_max_tokens,omitempty"`
+	Concurrency                       *int     `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	DynamicSearchLLM                  string   `yaml:"dynamic_search_llm,omitempty" json:"dynamic_search_llm,omitempty"`
+	DynamicSearchThreshold            *int     `yaml:"dynamic_search_threshold,omitempty" json:"dynamic_search_threshold,omitempty"`
+	DynamicSearchKeepParent           *bool    `yaml:"dynamic_search_keep_parent,omitempty" json:"dynamic_search_keep_parent,omitempty"`
+	DynamicSearchNumRepeats           *int     `yaml:"dynamic_search_num_repeats,omitempty" json:"dynamic_search_num_repeats,omitempty"`
+	DynamicSearchUseSummary           *bool    `yaml:"dynamic_search_use_summary,omitempty" json:"dynamic_search_use_summary,omitempty"`
+	DynamicSearchConcurrentCoroutines *int     `yaml:"dynamic_search_concurrent_coroutines,omitempty" json:"dynamic_search_concurrent_coroutines,omitempty"`
+	DynamicSearchMaxLevel             *int     `yaml:"dynamic_search_max_level,omitempty" json:"dynamic_search_max_level,omitempty"`
+}
+
+// DriftSearchConfig Drift 查询参数
+type DriftSearchConfig struct {
+	Prompt                        string   `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Temperature                   *float64 `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	TopP                          *float64 `yaml:"top_p,omitempty" json:"top_p,omitempty"`
+	N                             *int     `yaml:"n,omitempty" json:"n,omitempty"`
+	MaxTokens                     *int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	DataMaxTokens                 *int     `yaml:"data_max_tokens,omitempty" json:"data_max_tokens,omitempty"`
+	Concurrency                   *int     `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+	DriftKFollowups               *int     `yaml:"drift_k_followups,omitempty" json:"drift_k_followups,omitempty"`
+	PrimerFolds                   *int     `yaml:"primer_folds,omitempty" json:"primer_folds,omitempty"`
+	PrimerLLMMaxTokens            *int     `yaml:"primer_llm_max_tokens,omitempty" json:"primer_llm_max_tokens,omitempty"`
+	NDepth                        *int     `yaml:"n_depth,omitempty" json:"n_depth,omitempty"`
+	LocalSearchTextUnitProp       *float64 `yaml:"local_search_text_unit_prop,omitempty" json:"local_search_text_unit_prop,omitempty"`
+	LocalSearchCommunityProp      *float64 `yaml:"local_search_community_prop,omitempty" json:"local_search_community_prop,omitempty"`
+	LocalSearchTopKMappedEntities *int     `yaml:"local_search_top_k_mapped_entities,omitempty" json:"local_search_top_k_mapped_entities,omitempty"`
+	LocalSearchTopKRelationships  *int     `yaml:"local_search_top_k_relationships,omitempty" json:"local_search_top_k_relationships,omitempty"`
+	LocalSearchMaxDataTokens      *int     `yaml:"local_search_max_data_tokens,omitempty" json:"local_search_max_data_tokens,omitempty"`
+	LocalSearchTemperature        *float64 `yaml:"local_search_temperature,omitempty" json:"local_search_temperature,omitempty"`
+	LocalSearchTopP               *float64 `yaml:"local_search_top_p,omitempty" json:"local_search_top_p,omitempty"`
+	LocalSearchN                  *int     `yaml:"local_search_n,omitempty" json:"local_search_n,omitempty"`
+	LocalSearchLLMMaxGenTokens    *int     `yaml:"local_search_llm_max_gen_tokens,omitempty" json:"local_search_llm_max_gen_tokens,omitempty"`
+}
+
+var (
+	llmTypes     = []string{"openai_chat", "azure_openai_chat", "openai_embedding", "azure_openai_embedding", "static_response"}
+	asyncModes   = []string{"threaded", "asyncio"}
+	inputTypes   = []string{"file", "blob"}
+	fileTypes    = []string{"text", "csv"}
+	storageTypes = []string{"file", "memory", "blob", "cosmosdb", "console", "none"}
+)
+
+// configErrors 收集校验错误
+type configErrors []string
+
+func (e *configErrors) add(format string, args ...any) {
+	*e = append(*e, fmt.Sprintf(format, args...))
+}
+
+func (e *configErrors) oneOf(key, value string, options []string) {
+	if value != "" && !slices.Contains(options, value) {
+		e.add("%s: '%s' is not one of [%s]", key, value, strings.Join(options, ", "))
+	}
+}
+
+func (e *configErrors) intMin(key string, value *int, min int) {
+	if value != nil && *value < min {
+		e.add("%s: %d is less than %d", key, *value, min)
+	}
+}
+
+func (e *configErrors) floatRange(key string, value *float64, min, max float64) {
+	if value != nil && (*value < min || *value > max) {
+		e.add("%s: %g is out of range [%g, %g]", key, *value, min, max)
+	}
+}
+
+func (e *configErrors) llm(key string, llm *LLMConfig) {
+	if llm == nil {
+		return
+	}
+	e.oneOf(key+".type", llm.Type, llmTypes)
+	e.intMin(key+".max_tokens", llm.MaxTokens, 1)
+	e.floatRange(key+".temperature", llm.Temperature, 0, 2)
+	e.floatRange(key+".top_p", llm.TopP, 0, 1)
+	e.intMin(key+".n", llm.N, 1)
+	e.floatRange(key+".frequency_penalty", llm.FrequencyPenalty, -2, 2)
+	e.floatRange(key+".presence_penalty", llm.PresencePenalty, -2, 2)
+	e.intMin(key+".tokens_per_minute", llm.TokensPerMinute, 0)
+	e.intMin(key+".requests_per_minute", llm.RequestsPerMinute, 0)
+	e.intMin(key+".max_retries", llm.MaxRetries, 0)
+	e.intMin(key+".concurrent_requests", llm.ConcurrentRequests, 1)
+}
+
+func (e *configErrors) parallelization(key string, p *ParallelizationConfig) {
+	if p == nil {
+		return
+	}
+	if p.Stagger != nil && *p.Stagger < 0 {
+		e.add("%s.stagger: %g is less than 0", key, *p.Stagger)
+	}
+	e.intMin(key+".num_threads", p.NumThreads, 1)
+}
+
+func (e *configErrors) storage(key string, s *StorageConfig) {
+	if s == nil {
+		return
+	}
+	e.oneOf(key+".type", s.Type, storageTypes)
+	if s.Type == "blob" && s.ContainerName == "" {
+		e.add("%s.container_name: required when type is blob", key)
+	}
+}
+
+// Validate 校验配置的取值与范围
+func (cfg *GraphRAGConfig) Validate() []string {
+	errs := configErrors{}
+
+	e := &errs
+	e.oneOf("async_mode", cfg.AsyncMode, asyncModes)
+	e.llm("llm", cfg.LLM)
+	if cfg.LLM == nil {
+		e.add("llm: required")
+	} else if cfg.LLM.Model == "" {
+		e.add("llm.model: required")
+	}
+	e.parallelization("parallelization", cfg.Parallelization)
+
+	if em := cfg.Embeddings; em != nil {
+		e.oneOf("embeddings.async_mode", em.AsyncMode, asyncModes)
+		e.llm("embeddings.llm", em.LLM)
+		e.parallelization("embeddings.parallelization", em.Parallelization)
+		e.intMin("embeddings.batch_size", em.BatchSize, 1)
+		e.intMin("embeddings.batch_max_tokens", em.BatchMaxTokens, 1)
+		e.oneOf("embeddings.target", em.Target, []string{"required", "all", "none"})
+	}
+
+	if in := cfg.Input; in != nil {
+		e.oneOf("input.type", in.Type, inputTypes)
+		e.oneOf("input.file_type", in.FileType, fileTypes)
+		if in.FileType == "csv" && in.TextColumn == "" {
+			e.add("input.text_column: required when file_type is csv")
+		}
+	}
+
+	if ch := cfg.Chunks; ch != nil {
+		e.intMin("chunks.size", ch.Size, 1)
+		e.intMin("chunks.overlap", ch.Overlap, 0)
+		if ch.Size != nil && ch.Overlap != nil && *ch.Overlap >= *ch.Size {
+			e.add("chunks.overlap: %d must be less than chunks.size %d", *ch.Overlap, *ch.Size)
+		}
+	}
+
+	e.storage("cache", cfg.Cache)
+	e.storage("reporting", cfg.Reporting)
+	e.storage("storage", cfg.Storage)
+	e.storage("update_index_storage", cfg.UpdateIndexStorage)
+
+	if ee := cfg.EntityExtraction; ee != nil {
+		e.intMin("entity_extraction.max_gleanings", ee.MaxGleanings, 0)
+		for i, t := range ee.EntityTypes {
+			if strings.TrimSpace(t) == "" {
+				e.add("entity_extraction.entity_types[%d]: empty", i)
+			}
+		}
+		e.oneOf("entity_extraction.async_mode", ee.AsyncMode, asyncModes)
+		e.llm("entity_extraction.llm", ee.LLM)
+		e.parallelization("entity_extraction.parallelization", ee.Parallelization)
+	}
+
+	if sd := cfg.SummarizeDescriptions; sd != nil {
+		e.intMin("summarize_descriptions.max_length", sd.MaxLength, 1)
+		e.oneOf("summarize_descriptions.async_mode", sd.AsyncMode, asyncModes)
+		e.llm("summarize_descriptions.llm", sd.LLM)
+		e.parallelization("summarize_descriptions.parallelization", sd.Parallelization)
+	}
+
+	if ce := cfg.ClaimExtraction; ce != nil {
+		e.intMin("claim_extraction.max_gleanings", ce.MaxGleanings, 0)
+		e.oneOf("claim_extraction.async_mode", ce.AsyncMode, asyncModes)
+		e.llm("claim_extraction.llm", ce.LLM)
+		e.parallelization("claim_extraction.parallelization", ce.Parallelization)
+	}
+
+	if cr := cfg.CommunityReports; cr != nil {
+		e.intMin("community_reports.max_length", cr.MaxLength, 1)
+		e.intMin("community_reports.max_input_length", cr.MaxInputLength, 1)
+		e.oneOf("community_reports.async_mode", cr.AsyncMode, asyncModes)
+		e.llm("community_reports.llm", cr.LLM)
+		e.parallelization("community_reports.parallelization", cr.Parallelization)
+	}
+
+	if cg := cfg.ClusterGraph; cg != nil {
+		e.intMin("cluster_graph.max_cluster_size", cg.MaxClusterSize, 1)
+	}
+
+	if eg := cfg.EmbedGraph; eg != nil {
+		e.intMin("embed_graph.num_walks", eg.NumWalks, 1)
+		e.intMin("embed_graph.walk_length", eg.WalkLength, 1)
+		e.intMin("embed_graph.window_size", eg.WindowSize, 1)
+		e.intMin("embed_graph.iterations", eg.Iterations, 1)
+	}
+
+	if ls := cfg.LocalSearch; ls != nil {
+		e.floatRange("local_search.text_unit_prop", ls.TextUnitProp, 0, 1)
+		e.floatRange("local_search.community_prop", ls.CommunityProp, 0, 1)
+		if ls.TextUnitProp != nil && ls.CommunityProp != nil && *ls.TextUnitProp+*ls.CommunityProp > 1 {
+			e.add("local_search: text_unit_prop + community_prop must not exceed 1")
+		}
+		e.intMin("local_search.conversation_history_max_turns", ls.ConversationHistoryMaxTurns, 0)
+		e.intMin("local_search.top_k_entities", ls.TopKEntities, 1)
+		e.intMin("local_search.top_k_relationships", ls.TopKRelationships, 1)
+		e.floatRange("local_search.temperature", ls.Temperature, 0, 2)
+		e.floatRange("local_search.top_p", ls.TopP, 0, 1)
+		e.intMin("local_search.n", ls.N, 1)
+		e.intMin("local_search.max_tokens", ls.MaxTokens, 1)
+		e.intMin("local_search.llm_max_tokens", ls.LLMMaxTokens, 1)
+	}
+
+	if gs := cfg.GlobalSearch; gs != nil {
+		e.floatRange("global_search.temperature", gs.Temperature, 0, 2)
+		e.floatRange("global_search.top_p", gs.TopP, 0, 1)
+		e.intMin("global_search.n", gs.N, 1)
+		e.intMin("global_search.max_tokens", gs.MaxTokens, 1)
+		e.intMin("global_search.data_max_tokens", gs.DataMaxTokens, 1)
+		e.intMin("global_search.map_max_tokens", gs.MapMaxTokens, 1)
+		e.intMin("global_search.reduce_max_tokens", gs.ReduceMaxTokens, 1)
+		e.intMin("global_search.concurrency", gs.Concurrency, 1)
+		e.intMin("global_search.dynamic_search_threshold", gs.DynamicSearchThreshold, 0)
+		e.intMin("global_search.dynamic_search_num_repeats", gs.DynamicSearchNumRepeats, 1)
+		e.intMin("global_search.dynamic_search_max_level", gs.DynamicSearchMaxLevel, 0)
+	}
+
+	if ds := cfg.DriftSearch; ds != nil {
+		e.floatRange("drift_search.temperature", ds.Temperature, 0, 2)
+		e.floatRange("drift_search.top_p", ds.TopP, 0, 1)
+		e.intMin("drift_search.n", ds.N, 1)
+		e.intMin("drift_search.max_tokens", ds.MaxTokens, 1)
+		e.intMin("drift_search.concurrency", ds.Concurrency, 1)
+		e.intMin("drift_search.drift_k_followups", ds.DriftKFollowups, 1)
+		e.intMin("drift_search.primer_folds", ds.PrimerFolds, 1)
+		e.intMin("drift_search.n_depth", ds.NDepth, 1)
+		e.floatRange("drift_search.local_search_text_unit_prop", ds.LocalSearchTextUnitProp, 0, 1)
+		e.floatRange("drift_search.local_search_community_prop", ds.LocalSearchCommunityProp, 0, 1)
+		e.floatRange("drift_search.local_search_temperature", ds.LocalSearchTemperature, 0, 2)
+		e.floatRange("drift_search.local_search_top_p", ds.LocalSearchTopP, 0, 1)
+	}
+
+	return errs
+}
+
+// unknownKeys 对照配置结构体找出 yaml 中未定义的键
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return unknownKeys(node.Content[0], t, prefix)
+	}
+	if node.Kind != yaml.MappingNode || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+
+	keys := []string{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		ft, ok := fields[key]
+		if !ok {
+			keys = append(keys, prefix+key)
+			continue
+		}
+		keys = append(keys, unknownKeys(node.Content[i+1], ft, prefix+key+".")...)
+	}
+	return keys
+}
@@ -0,0 +1,51 @@
+package api
+
+import "strings"
+
+// traditionalPairs 常用繁体字与对应的简体字，每两个字为一组，用于搜索时统一为简体
+const traditionalPairs = "萬万與与醜丑專专業业叢丛東东絲丝兩两嚴严喪丧個个豐丰臨临為为麗丽舉举麼么義义烏乌樂乐喬乔習习鄉乡書书買买亂乱爭争虧亏雲云" +
+	"亞亚產产畝亩親亲億亿僅仅從从侖仑倉仓儀仪們们價价眾众優优夥伙會会傘伞偉伟傳传傷伤倫伦偽伪體体餘余傭佣俠侠侶侣僥侥偵侦側侧" +
+	"僑侨儈侩儂侬儉俭債债傾倾償偿儲储兒儿兌兑黨党蘭兰關关興兴茲兹養养獸兽內内岡冈冊册寫写軍军農农馮冯衝冲決决況况凍冻淨净涼凉" +
+	"減减湊凑凜凛幾几鳳凤憑凭凱凯擊击鑿凿劃划劉刘則则剛刚創创刪删別别劑剂剮剐劍剑剝剥劇剧勸劝辦办務务動动勵励勁劲勞劳勢势勳勋" +
+	"勻匀匱匮區区醫医華华協协單单賣卖盧卢衛卫卻却廠厂廳厅歷历曆历厲厉壓压厭厌縣县參参雙双變变敘叙疊叠葉叶號号嘆叹嚇吓呂吕嗎吗" +
+	"噸吨聽听啟启吳吴嘔呕唄呗員员嗆呛嗚呜詠咏嚨咙響响啞哑嘩哗噴喷團团園园圍围圖图國国圓圆聖圣場场壞坏塊块堅坚壇坛壩坝塢坞墳坟" +
+	"墜坠壟垄壘垒墾垦執执報报塗涂壺壶壽寿夢梦夾夹奪夺獎奖奧奥妝妆婦妇媽妈婁娄嬌娇孫孙學学孿孪寧宁寶宝實实寵宠審审憲宪宮宫寬宽" +
+	"賓宾寢寝對对尋寻導导將将爾尔塵尘嘗尝堯尧尷尴屍尸盡尽層层屆届屬属屢屡嶼屿歲岁豈岂嶇岖崗岗嵐岚島岛嶺岭鞏巩幣币帥帅師师帳帐" +
+	"幟帜帶带幫帮幹干乾干廣广莊庄慶庆廬庐庫库應应廟庙龐庞廢废開开異异棄弃張张彌弥彎弯彈弹強强歸归當当錄录彥彦徹彻徑径後后憶忆" +
+	"憂忧懷怀態态憐怜總总戀恋懇恳惡恶惱恼悅悦懸悬驚惊懼惧慘惨懲惩憊惫慚惭慣惯願愿懾慑戰战戲戏戶户拋抛挾挟搶抢護护擔担擬拟攏拢" +
+	"揀拣擁拥攔拦擰拧撥拨擇择掛挂摯挚揮挥撓挠擋挡擠挤撈捞損损撿捡換换搗捣據据擄掳擲掷撣掸攙搀攬揽攪搅擱搁摟搂攜携攝摄擺摆搖摇" +
+	"攤摊撐撑擴扩擾扰揚扬斂敛數数齋斋鬥斗斬斩斷断無无舊旧時时曠旷晝昼顯显晉晋曬晒曉晓暈晕暉晖暫暂術术樸朴機机殺杀雜杂權权條条" +
+	"來来楊杨傑杰極极構构樞枢棗枣檸柠柵栅標标棧栈棟栋欄栏樹树樣样檔档橋桥樁桩檢检槨椁橢椭樓楼欖榄櫚榈檻槛檳槟橫横櫻樱櫥橱櫃柜" +
+	"簷檐歡欢歐欧殲歼殘残殯殡毆殴毀毁畢毕斃毙氈毡氣气氫氢氬氩匯汇彙汇漢汉湯汤溝沟沒没滄沧瀋沈淪沦滬沪潑泼澤泽潔洁灑洒澆浇濁浊" +
+	"測测濟济瀏浏渾浑濃浓濤涛澇涝潤润漲涨澀涩淵渊漁渔漸渐瀉泻溫温灣湾濕湿潰溃濺溅滯滞滲渗滷卤灘滩濾滤濫滥瀾澜滿满瀕濒燈灯靈灵" +
+	"災灾燦灿爐炉燉炖點点煉炼熾炽爍烁爛烂烴烃燭烛煙烟煩烦燒烧燴烩燙烫燼烬熱热愛爱爺爷牽牵犧牺狀状猶犹狽狈獨独狹狭獅狮獄狱獵猎" +
+	"貓猫獻献瑪玛環环現现璽玺瑣琐瓊琼瑤瑶甕瓮電电畫画暢畅療疗瘡疮瘋疯癥症痙痉癢痒瘓痪癱瘫癮瘾癡痴皺皱盞盏監监盤盘睜睁瞞瞒礦矿" +
+	"碼码磚砖確确礙碍禮礼禍祸離离禿秃種种積积稱称穩稳穀谷窮穷竊窃窯窑竄窜窩窝豎竖競竞筆笔筍笋籠笼節节範范築筑簡简籃篮籬篱類类" +
+	"糧粮緊紧糾纠紀纪約约紅红紋纹納纳紐纽純纯紗纱紙纸級级紛纷紡纺練练組组紳绅細细織织終终紹绍經经綁绑結结絨绒給给絡络絕绝統统" +
+	"絹绢繼继續续緒绪綺绮綱纲網网維维綿绵綜综綻绽綠绿緞缎締缔編编緩缓緯纬緻致縛缚縫缝縮缩縱纵績绩繃绷繞绕繩绳繪绘繡绣繳缴纏缠" +
+	"纖纤線线纜缆緣缘罰罚罷罢羅罗翹翘聞闻聯联聰聪聲声聳耸職职聶聂膠胶脅胁脈脉膽胆勝胜脹胀腦脑臟脏髒脏膩腻腫肿腳脚膚肤腎肾臘腊" +
+	"臉脸艦舰艙舱艷艳藝艺蘇苏蘋苹莖茎薦荐藥药萊莱蓮莲獲获穫获蒼苍蓋盖蔔卜蔣蒋薩萨藍蓝蘆芦蘊蕴蟲虫雖虽蝦虾螞蚂蠶蚕蠻蛮蠅蝇螢萤" +
+	"補补襯衬裝装襲袭複复復复褲裤裡里裏里見见觀观規规覓觅視视覽览覺觉觸触計计訂订認认討讨讓让訓训議议記记講讲許许論论設设訪访" +
+	"證证評评識识詐诈訴诉診诊詞词譯译試试詩诗誠诚話话誕诞詢询該该詳详誤误說说請请諸诸諾诺讀读課课誰谁調调談谈誼谊謀谋謊谎謎谜" +
+	"謙谦謝谢謠谣謹谨譜谱讚赞諧谐貝贝負负貢贡財财責责賢贤敗败賬账貨货質质販贩貪贪貧贫購购貯贮貫贯費费貼贴貴贵貸贷貿贸賀贺資资" +
+	"賊贼賄贿賠赔賞赏賦赋賤贱賴赖賽赛贈赠贊赞贏赢趕赶趙赵趨趋躍跃跡迹踐践蹤踪車车軌轨軒轩轉转軟软輪轮軸轴較较載载輔辅輕轻輛辆" +
+	"輝辉輸输轄辖轟轰辭辞邊边遼辽達达遷迁過过邁迈運运還还這这進进遠远違违連连遲迟適适選选遞递遺遗鄧邓鄰邻鄭郑醬酱釀酿釋释鑒鉴" +
+	"針针釘钉鈣钙鋼钢鉤钩鈕钮鈴铃鉛铅銀银銅铜鋁铝銷销鎖锁鋒锋鋪铺鏈链鍋锅錯错錢钱鍵键錦锦鍍镀鎮镇鏡镜鐘钟鍾钟鐵铁鑄铸鑰钥錶表" +
+	"長长門门閃闪閉闭問问閑闲間间閘闸閣阁閱阅闊阔闖闯閥阀隊队陽阳陰阴陣阵階阶際际陸陆陳陈險险隨随隱隐隸隶難难雞鸡霧雾靜静韓韩" +
+	"頁页頂顶項项順顺須须預预頑顽頓顿頒颁領领頻频題题額额顏颜顧顾顛颠風风飛飞飯饭飲饮飽饱飾饰館馆饑饥饋馈馬马駕驾駐驻驗验騎骑" +
+	"騰腾驅驱驟骤髮发發发鬆松鬧闹鬱郁鬍胡魚鱼鮮鲜鳥鸟鳴鸣鴨鸭鵝鹅鷹鹰鹽盐麥麦麵面黃黄齊齐齒齿齣出龍龙龜龟臺台颱台廈厦誌志製制" +
+	"準准係系繫系嚮向隻只週周佈布瞭了傢家僕仆醃腌讎仇鐲镯壯壮瀝沥藹蔼靄霭"
+
+var simplifiedReplacer = func() *strings.Replacer {
+	runes := []rune(traditionalPairs)
+	oldnew := make([]string, 0, len(runes))
+	for _, r := range runes {
+		oldnew = append(oldnew, string(r))
+	}
+	return strings.NewReplacer(oldnew...)
+}()
+
+// toSimplified 将常用繁体字转为简体字，不在对照表中的字保持不变
+func toSimplified(s string) string {
+	return simplifiedReplacer.Replace(s)
+}
@@ -0,0 +1,446 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"graphraggo/internal/global"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	JobImportArchive = "import-archive"
+	JobImportDir     = "import-dir"
+)
+
+// ImportSkip 导入时跳过的文件
+type ImportSkip struct {
+	Path   string `json:"path"` // 压缩包或目录中的相对路径
+	Reason string `json:"reason"`
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Imported []UploadFileResult `json:"imported"`
+	Skipped  []ImportSkip       `json:"skipped"`
+}
+
+// importEntry 压缩包或目录中的一个文件
+type importEntry struct {
+	path string
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+// importName 将压缩包或目录中的相对路径转换为 input 目录下的文件名
+// 子目录以 _ 连接，如 manuals/2024/a.pdf 导入为 manuals_2024_a.pdf；
+// 绝对路径、包含 .. 的路径以及隐藏文件返回错误
+func importName(rel string) (string, error) {
+	rel = strings.ReplaceAll(rel, `\`, "/")
+	if path.IsAbs(rel) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("absolute path")
+	}
+	parts := []string{}
+	for _, part := range strings.Split(path.Clean(rel), "/") {
+		switch {
+		case part == "..":
+			return "", fmt.Errorf("path escapes the archive")
+		case part == "__MACOSX" || strings.HasPrefix(part, "."):
+			return "", fmt.Errorf("hidden file")
+		case part != "":
+			parts = append(parts, part)
+		}
+	}
+	name := strings.Join(parts, "_")
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// importFile 导入单个文件，经过与上传相同的检查、检测、去重与转换
+func importFile(kb string, entry importEntry) (*UploadFileResult, error) {
+	name, err := importName(entry.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkUpload(kb, name, entry.size); err != nil {
+		return nil, err
+	}
+
+	input := kbPath(kb, "input")
+	if err := os.MkdirAll(input, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	rc, err := entry.open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(input, "."+name+".*.uploading")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// 压缩包中记录的大小不可信，最多读取 size+1 字节，防止解压炸弹
+	n, err := io.Copy(tmp, io.LimitReader(rc, entry.size+1))
+	tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+	if n != entry.size {
+		return nil, fmt.Errorf("size mismatch, expected %d, got %d", entry.size, n)
+	}
+
+	result, _, err := CommitUpload(kb, name, tmp.Name())
+	return result, err
+}
+
+// runImport 逐个导入文件，单个文件失败时跳过
+func runImport(ctx context.Context, job *Job, kb string, entries []importEntry) (*ImportResult, error) {
+	result := ImportResult{Imported: []UploadFileResult{}, Skipped: []ImportSkip{}}
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return &result, err
+		}
+		job.SetProgress(float64(i)/float64(len(entries)),
+			fmt.Sprintf("importing %d/%d: %s", i+1, len(entries), entry.path))
+
+		r, err := importFile(kb, entry)
+		if err != nil {
+			result.Skipped = append(result.Skipped, ImportSkip{Path: entry.path, Reason: err.Error()})
+			job.Log(fmt.Sprintf("skipped %s: %s", entry.path, err.Error()))
+			continue
+		}
+		result.Imported = append(result.Imported, *r)
+		job.Log(fmt.Sprintf("imported %s as %s", entry.path, r.Name))
+	}
+	return &result, nil
+}
+
+// zipEntries 读取 zip 中的文件
+func zipEntries(zr *zip.Reader) []importEntry {
+	entries := []importEntry{}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		entries = append(entries, importEntry{
+			path: f.Name,
+			size: int64(f.UncompressedSize64),
+			open: f.Open,
+		})
+	}
+	return entries
+}
+
+// importTarGz 流式导入 tar.gz 中的文件，进度按已读取的压缩包字节数计算
+func importTarGz(ctx context.Context, job *Job, kb, archive string) (*ImportResult, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &countingReader{r: f}
+	gz, err := gzip.NewReader(counter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar.gz: %w", err)
+	}
+	defer gz.Close()
+
+	result := ImportResult{Imported: []UploadFileResult{}, Skipped: []ImportSkip{}}
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return &result, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &result, fmt.Errorf("failed to read tar.gz: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		job.SetProgress(float64(counter.n)/float64(stat.Size()), fmt.Sprintf("importing %s", hdr.Name))
+		r, err := importFile(kb, importEntry{
+			path: hdr.Name,
+			size: hdr.Size,
+			open: func() (io.ReadCloser, error) { return io.NopCloser(tr), nil },
+		})
+		if err != nil {
+			result.Skipped = append(result.Skipped, ImportSkip{Path: hdr.Name, Reason: err.Error()})
+			job.Log(fmt.Sprintf("skipped %s: %s", hdr.Name, err.Error()))
+			continue
+		}
+		result.Imported = append(result.Imported, *r)
+		job.Log(fmt.Sprintf("imported %s as %s", hdr.Name, r.Name))
+	}
+	return &result, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// archiveType 根据文件名判断压缩包类型
+func archiveType(filename string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+// ImportArchive 在后台导入压缩包，archive 导入结束后删除
+func ImportArchive(kb, archive, filename string) (*Job, error) {
+	kind := archiveType(filename)
+	if kind == "" {
+		return nil, fmt.Errorf("unsupported archive '%s', only .zip, .tar.gz and .tgz are supported", filename)
+	}
+
+	return StartJob(JobImportArchive, kb, func(ctx context.Context, job *Job) (any, error) {
+		defer os.Remove(archive)
+
+		if kind == "tar.gz" {
+			return importTarGz(ctx, job, kb, archive)
+		}
+
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer zr.Close()
+		return runImport(ctx, job, kb, zipEntries(&zr.Reader))
+	})
+}
+
+// allowedImportDir 检查目录是否位于 global.ImportDirs 允许的目录下，返回解析符号链接后的绝对路径
+func allowedImportDir(dir string) (string, error) {
+	if len(global.ImportDirs) == 0 {
+		return "", fmt.Errorf("importing from server directories is disabled")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("dir '%s' not exists", dir)
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("'%s' is not a directory", dir)
+	}
+
+	for _, allowed := range global.ImportDirs {
+		root, err := filepath.EvalSymlinks(allowed)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("dir '%s' is not in the allowed import directories", dir)
+}
+
+// dirEntries 读取目录中的文件，不跟随符号链接
+func dirEntries(root string, recursive bool) ([]importEntry, error) {
+	entries := []importEntry{}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, importEntry{
+			path: filepath.ToSlash(rel),
+			size: info.Size(),
+			open: func() (io.ReadCloser, error) { return os.Open(p) },
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// ImportDir 在后台从服务器目录导入文件，原文件保留
+func ImportDir(kb, dir string, recursive bool) (*Job, error) {
+	root, err := allowedImportDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return StartJob(JobImportDir, kb, func(ctx context.Context, job *Job) (any, error) {
+		job.SetProgress(0, "scanning directory")
+		entries, err := dirEntries(root, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return runImport(ctx, job, kb, entries)
+	})
+}
+
+// ImportArchive 上传 zip 或 tar.gz 压缩包，在后台解压导入到 input 目录
+func (ka *KBApi) ImportArchive(c *gin.Context) {
+	type ImportArchiveRsp struct {
+		BaseRsp
+		JobID string `json:"job_id"`
+	}
+
+	rsp := ImportArchiveRsp{}
+
+	kb := c.PostForm("kb")
+	if err := checkKB(kb); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if archiveType(file.Filename) == "" {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("unsupported archive '%s', only .zip, .tar.gz and .tgz are supported", file.Filename)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 保存到 uploads 目录，任务结束后删除
+	if err := os.MkdirAll(uploadPath(), os.ModePerm); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	tmp, err := os.CreateTemp(uploadPath(), "import-*")
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	tmp.Close()
+	if err := c.SaveUploadedFile(file, tmp.Name()); err != nil {
+		os.Remove(tmp.Name())
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	job, err := ImportArchive(kb, tmp.Name(), file.Filename)
+	if err != nil {
+		os.Remove(tmp.Name())
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusConflict, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.JobID = job.ID
+	c.JSON(http.StatusOK, rsp)
+}
+
+// ImportDir 从服务器上允许的目录导入文件
+func (ka *KBApi) ImportDir(c *gin.Context) {
+	type ImportDirReq struct {
+		KB        string `json:"kb"`
+		Dir       string `json:"dir"`
+		Recursive bool   `json:"recursive"`
+	}
+	type ImportDirRsp struct {
+		BaseRsp
+		JobID string `json:"job_id"`
+	}
+
+	req := ImportDirReq{}
+	rsp := ImportDirRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if _, err := allowedImportDir(req.Dir); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusForbidden, rsp)
+		return
+	}
+
+	job, err := ImportDir(req.KB, req.Dir, req.Recursive)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusConflict, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.JobID = job.ID
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,488 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 变化类型
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// 对比的内容
+const (
+	DiffEntities      = "entities"
+	DiffRelationships = "relationships"
+	DiffCommunities   = "communities"
+)
+
+const (
+	// communityMatchThreshold 成员的 Jaccard 相似度不低于该值时视为同一社区
+	communityMatchThreshold = 0.5
+	// communityChangeThreshold 成员的 Jaccard 相似度低于该值时视为成员明显变化
+	communityChangeThreshold = 0.8
+	// reportChangeThreshold 报告摘要的词 Jaccard 相似度低于该值时视为报告明显变化
+	reportChangeThreshold = 0.5
+	// ratingChangeThreshold 报告评分的变化不低于该值时视为报告明显变化
+	ratingChangeThreshold = 1.0
+)
+
+// EntityChange 实体的变化，以 title 为键
+type EntityChange struct {
+	Title           string `json:"title"`
+	Change          string `json:"change"`
+	Type            string `json:"type,omitempty"`
+	BaseType        string `json:"base_type,omitempty"`
+	Description     string `json:"description,omitempty"`
+	BaseDescription string `json:"base_description,omitempty"`
+}
+
+// RelationshipChange 关系的变化，以两端实体的 title 为键，与方向无关
+type RelationshipChange struct {
+	Source      string  `json:"source"`
+	Target      string  `json:"target"`
+	Change      string  `json:"change"`
+	Weight      float64 `json:"weight"`
+	Description string  `json:"description"`
+}
+
+// CommunityChange 社区的变化。社区编号在不同索引版本之间不稳定，按同一层级中成员最相似的社区匹配
+type CommunityChange struct {
+	Level          int      `json:"level"`
+	Change         string   `json:"change"`
+	Community      string   `json:"community,omitempty"`      // 新版本中的社区编号
+	BaseCommunity  string   `json:"base_community,omitempty"` // 旧版本中的社区编号
+	Title          string   `json:"title,omitempty"`
+	BaseTitle      string   `json:"base_title,omitempty"`
+	Similarity     float64  `json:"similarity"`        // 成员的 Jaccard 相似度
+	Reasons        []string `json:"reasons,omitempty"` // membership 或 report
+	MembersAdded   []string `json:"members_added,omitempty"`
+	MembersRemoved []string `json:"members_removed,omitempty"`
+}
+
+// DiffSummary 各类变化的数量
+type DiffSummary struct {
+	EntitiesAdded        int `json:"entities_added"`
+	EntitiesRemoved      int `json:"entities_removed"`
+	EntitiesChanged      int `json:"entities_changed"`
+	RelationshipsAdded   int `json:"relationships_added"`
+	RelationshipsRemoved int `json:"relationships_removed"`
+	CommunitiesAdded     int `json:"communities_added"`
+	CommunitiesRemoved   int `json:"communities_removed"`
+	CommunitiesChanged   int `json:"communities_changed"`
+}
+
+// GraphDiff 两个索引版本的差异，各列表按键排序
+type GraphDiff struct {
+	Summary       DiffSummary          `json:"summary"`
+	Entities      []EntityChange       `json:"entities"`
+	Relationships []RelationshipChange `json:"relationships"`
+	Communities   []CommunityChange    `json:"communities"`
+}
+
+// graphDiffCache 两个索引版本的差异，图与社区均未重新读取时复用
+type graphDiffCache struct {
+	base, target     *Graph
+	baseCs, targetCs *Communities
+	diff             *GraphDiff
+}
+
+var (
+	graphDiffs   = map[string]graphDiffCache{}
+	graphDiffMux sync.Mutex
+)
+
+func relationshipKey(source, target string) [2]string {
+	if source > target {
+		source, target = target, source
+	}
+	return [2]string{source, target}
+}
+
+// jaccard 计算两个集合的 Jaccard 相似度
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	n := 0
+	for k := range a {
+		if b[k] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(a)+len(b)-n)
+}
+
+func setOf(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// wordsOf 文本的词集合，汉字按字切分
+func wordsOf(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, field := range strings.Fields(normalizeSearch(text)) {
+		if hasHan(field) {
+			for _, r := range field {
+				words[string(r)] = true
+			}
+			continue
+		}
+		words[strings.Trim(field, ".,;:!?()\"'")] = true
+	}
+	return words
+}
+
+// diffEntities 对比实体，类型或描述不同时视为变化
+func diffEntities(base, target *Graph) []EntityChange {
+	changes := []EntityChange{}
+	for title, node := range target.Nodes {
+		old, ok := base.Nodes[title]
+		switch {
+		case !ok:
+			changes = append(changes, EntityChange{Title: title, Change: DiffAdded, Type: node.Type, Description: node.Description})
+		case old.Type != node.Type || strings.TrimSpace(old.Description) != strings.TrimSpace(node.Description):
+			changes = append(changes, EntityChange{
+				Title:           title,
+				Change:          DiffChanged,
+				Type:            node.Type,
+				BaseType:        old.Type,
+				Description:     node.Description,
+				BaseDescription: old.Description,
+			})
+		}
+	}
+	for title, node := range base.Nodes {
+		if _, ok := target.Nodes[title]; !ok {
+			changes = append(changes, EntityChange{Title: title, Change: DiffRemoved, BaseType: node.Type, BaseDescription: node.Description})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Title < changes[j].Title })
+	return changes
+}
+
+// diffRelationships 对比关系，两端实体相同的关系视为同一关系
+func diffRelationships(base, target *Graph) []RelationshipChange {
+	keys := func(g *Graph) map[[2]string]GraphEdge {
+		edges := map[[2]string]GraphEdge{}
+		for _, e := range g.Edges {
+			edges[relationshipKey(e.Source, e.Target)] = e
+		}
+		return edges
+	}
+	baseEdges, targetEdges := keys(base), keys(target)
+
+	changes := []RelationshipChange{}
+	for key, e := range targetEdges {
+		if _, ok := baseEdges[key]; !ok {
+			changes = append(changes, RelationshipChange{Source: key[0], Target: key[1], Change: DiffAdded, Weight: e.Weight, Description: e.Description})
+		}
+	}
+	for key, e := range baseEdges {
+		if _, ok := targetEdges[key]; !ok {
+			changes = append(changes, RelationshipChange{Source: key[0], Target: key[1], Change: DiffRemoved, Weight: e.Weight, Description: e.Description})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Source != changes[j].Source {
+			return changes[i].Source < changes[j].Source
+		}
+		return changes[i].Target < changes[j].Target
+	})
+	return changes
+}
+
+// reportChanged 社区报告是否明显变化
+func reportChanged(base, target *CommunityReport) bool {
+	if base == nil || target == nil {
+		return (base == nil) != (target == nil)
+	}
+	if base.Rating != nil && target.Rating != nil && math.Abs(*base.Rating-*target.Rating) >= ratingChangeThreshold {
+		return true
+	}
+	return jaccard(wordsOf(base.Summary), wordsOf(target.Summary)) < reportChangeThreshold
+}
+
+// diffCommunities 对比社区：同一层级中按成员相似度从高到低贪心匹配，
+// 没有匹配的为新增或删除，匹配后成员或报告明显变化的为变化
+func diffCommunities(base, target *Communities) []CommunityChange {
+	type pair struct {
+		base, target *Community
+		similarity   float64
+	}
+
+	members := map[*Community]map[string]bool{}
+	for _, c := range append(slices.Clone(base.List), target.List...) {
+		members[c] = setOf(c.entities)
+	}
+
+	// 只计算至少有一个共同成员的社区
+	pairs := []pair{}
+	for _, t := range target.List {
+		seen := map[*Community]bool{}
+		for _, title := range t.entities {
+			for _, b := range base.byTitle[title] {
+				if b.Level != t.Level || seen[b] {
+					continue
+				}
+				seen[b] = true
+				if s := jaccard(members[b], members[t]); s >= communityMatchThreshold {
+					pairs = append(pairs, pair{base: b, target: t, similarity: s})
+				}
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].similarity > pairs[j].similarity })
+
+	changes := []CommunityChange{}
+	matched := map[*Community]bool{}
+	for _, p := range pairs {
+		if matched[p.base] || matched[p.target] {
+			continue
+		}
+		matched[p.base], matched[p.target] = true, true
+
+		change := CommunityChange{
+			Level:         p.target.Level,
+			Change:        DiffChanged,
+			Community:     p.target.ID,
+			BaseCommunity: p.base.ID,
+			Title:         p.target.Title,
+			BaseTitle:     p.base.Title,
+			Similarity:    p.similarity,
+		}
+		if p.similarity < communityChangeThreshold {
+			change.Reasons = append(change.Reasons, "membership")
+		}
+		if reportChanged(base.reports[p.base.ID], target.reports[p.target.ID]) {
+			change.Reasons = append(change.Reasons, "report")
+		}
+		if len(change.Reasons) == 0 {
+			continue
+		}
+		for title := range members[p.target] {
+			if !members[p.base][title] {
+				change.MembersAdded = append(change.MembersAdded, title)
+			}
+		}
+		for title := range members[p.base] {
+			if !members[p.target][title] {
+				change.MembersRemoved = append(change.MembersRemoved, title)
+			}
+		}
+		sort.Strings(change.MembersAdded)
+		sort.Strings(change.MembersRemoved)
+		changes = append(changes, change)
+	}
+
+	for _, t := range target.List {
+		if !matched[t] {
+			changes = append(changes, CommunityChange{Level: t.Level, Change: DiffAdded, Community: t.ID, Title: t.Title, MembersAdded: slices.Sorted(maps.Keys(members[t]))})
+		}
+	}
+	for _, b := range base.List {
+		if !matched[b] {
+			changes = append(changes, CommunityChange{Level: b.Level, Change: DiffRemoved, BaseCommunity: b.ID, BaseTitle: b.Title, MembersRemoved: slices.Sorted(maps.Keys(members[b]))})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Level != changes[j].Level {
+			return changes[i].Level < changes[j].Level
+		}
+		a, b := changes[i].Community, changes[j].Community
+		if a == "" || b == "" {
+			return a != ""
+		}
+		return compareValues(numberOf(a), numberOf(b)) < 0
+	})
+	return changes
+}
+
+// DiffIndexVersions 对比知识库的两个索引版本，任一版本没有社区表时不对比社区
+func DiffIndexVersions(kb, base, target string) (*GraphDiff, error) {
+	baseGraph, err := ReadGraph(kb, base)
+	if err != nil {
+		return nil, err
+	}
+	targetGraph, err := ReadGraph(kb, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// 没有社区表时为 nil，不对比社区；社区表读取失败时返回错误，不当作没有社区
+	baseCs, err := ReadCommunities(kb, base)
+	if err != nil && !errors.Is(err, errTableNotFound) {
+		return nil, err
+	}
+	targetCs, err := ReadCommunities(kb, target)
+	if err != nil && !errors.Is(err, errTableNotFound) {
+		return nil, err
+	}
+
+	key := kb + "/" + base + "#" + target
+	graphDiffMux.Lock()
+	cache, ok := graphDiffs[key]
+	graphDiffMux.Unlock()
+	if ok && cache.base == baseGraph && cache.target == targetGraph && cache.baseCs == baseCs && cache.targetCs == targetCs {
+		return cache.diff, nil
+	}
+
+	d := GraphDiff{
+		Entities:      diffEntities(baseGraph, targetGraph),
+		Relationships: diffRelationships(baseGraph, targetGraph),
+		Communities:   []CommunityChange{},
+	}
+	if baseCs != nil && targetCs != nil {
+		d.Communities = diffCommunities(baseCs, targetCs)
+	}
+
+	for _, c := range d.Entities {
+		switch c.Change {
+		case DiffAdded:
+			d.Summary.EntitiesAdded++
+		case DiffRemoved:
+			d.Summary.EntitiesRemoved++
+		case DiffChanged:
+			d.Summary.EntitiesChanged++
+		}
+	}
+	for _, c := range d.Relationships {
+		switch c.Change {
+		case DiffAdded:
+			d.Summary.RelationshipsAdded++
+		case DiffRemoved:
+			d.Summary.RelationshipsRemoved++
+		}
+	}
+	for _, c := range d.Communities {
+		switch c.Change {
+		case DiffAdded:
+			d.Summary.CommunitiesAdded++
+		case DiffRemoved:
+			d.Summary.CommunitiesRemoved++
+		case DiffChanged:
+			d.Summary.CommunitiesChanged++
+		}
+	}
+
+	graphDiffMux.Lock()
+	graphDiffs[key] = graphDiffCache{base: baseGraph, target: targetGraph, baseCs: baseCs, targetCs: targetCs, diff: &d}
+	graphDiffMux.Unlock()
+	return &d, nil
+}
+
+// pageOf 获取按 change 过滤后的一页
+func pageOf[T any](items []T, changeOf func(T) string, change string, page, pageSize int) ([]T, int) {
+	if change != "" {
+		items = slices.DeleteFunc(slices.Clone(items), func(item T) bool { return changeOf(item) != change })
+	}
+	start, end := pageRange(len(items), page, pageSize)
+	return items[start:end], len(items)
+}
+
+// DiffData 对比两个索引版本，返回各类变化的数量与指定内容的一页变化
+func (da *DataApi) DiffData(c *gin.Context) {
+	type DiffDataReq struct {
+		KB       string `json:"kb"`
+		Base     string `json:"base"`     // 旧版本，为空时为当前的 output
+		Target   string `json:"target"`   // 新版本，为空时为当前的 output
+		Category string `json:"category"` // entities、relationships 或 communities，默认 entities
+		Change   string `json:"change"`   // added、removed 或 changed，为空时返回所有变化
+		Page     int    `json:"page"`
+		PageSize int    `json:"page_size"`
+	}
+	type DiffDataRsp struct {
+		BaseRsp
+		Summary  DiffSummary `json:"summary"`
+		Category string      `json:"category"`
+		Total    int         `json:"total"`
+		Page     int         `json:"page"`
+		PageSize int         `json:"page_size"`
+		Items    any         `json:"items"`
+	}
+
+	req := DiffDataReq{}
+	rsp := DiffDataRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if req.Category == "" {
+		req.Category = DiffEntities
+	}
+	if !slices.Contains([]string{DiffEntities, DiffRelationships, DiffCommunities}, req.Category) {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("category '%s' is invalid", req.Category)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if req.Change != "" && !slices.Contains([]string{DiffAdded, DiffRemoved, DiffChanged}, req.Change) {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("change '%s' is invalid", req.Change)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if (req.Base == "" || req.Base == defaultDB) && (req.Target == "" || req.Target == defaultDB) || req.Base == req.Target {
+		rsp.Code = -1
+		rsp.Msg = "base and target must be different"
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	d, err := DiffIndexVersions(req.KB, req.Base, req.Target)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Page = max(req.Page, 1)
+	rsp.PageSize = limitOf(req.PageSize, defaultTablePageSize, maxTablePageSize)
+	switch req.Category {
+	case DiffEntities:
+		rsp.Items, rsp.Total = pageOf(d.Entities, func(c EntityChange) string { return c.Change }, req.Change, rsp.Page, rsp.PageSize)
+	case DiffRelationships:
+		rsp.Items, rsp.Total = pageOf(d.Relationships, func(c RelationshipChange) string { return c.Change }, req.Change, rsp.Page, rsp.PageSize)
+	case DiffCommunities:
+		rsp.Items, rsp.Total = pageOf(d.Communities, func(c CommunityChange) string { return c.Change }, req.Change, rsp.Page, rsp.PageSize)
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Summary = d.Summary
+	rsp.Category = req.Category
+	c.JSON(http.StatusOK, rsp)
+}
+
+// evictGraphDiffs 删除与匹配的索引版本相关的差异，键为 kb/base#target，任一版本匹配即删除
+func evictGraphDiffs(m cacheMatcher) {
+	graphDiffMux.Lock()
+	defer graphDiffMux.Unlock()
+	for key := range graphDiffs {
+		if pair, ok := strings.CutPrefix(key, m.kb+"/"); ok {
+			base, target, _ := strings.Cut(pair, "#")
+			if m.matchDB(base) || m.matchDB(target) {
+				delete(graphDiffs, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"graphraggo/internal/global"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	indexRunFile = "index_runs.json"
+
+	// indexVersionDir 成功的索引将 output 与 logs 归档到该目录下以索引 ID 命名的目录，作为索引版本
+	indexVersionDir = "versions"
+)
+
+// 索引运行状态
+const (
+	IndexRunRunning   = "running"
+	IndexRunSucceeded = "succeeded"
+	IndexRunFailed    = "failed"
+)
+
+// IndexRun 一次建立索引的记录
+type IndexRun struct {
+	ID         string            `json:"id"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	Inputs     map[string]string `json:"inputs"`           // 使用的输入文件版本，key 为文件名
+	LogOffset  int64             `json:"log_offset"`       // 开始时索引日志的大小，graphrag 追加写入日志，本次索引的日志从该偏移开始
+	Pruned     bool              `json:"pruned,omitempty"` // 索引版本超过保留数量，已被删除
+}
+
+var indexRunLock sync.Mutex
+
+// ReadIndexRuns 获取知识库的索引记录，按开始时间排序
+func ReadIndexRuns(kb string) ([]IndexRun, error) {
+	runs := []IndexRun{}
+	data, err := os.ReadFile(kbPath(kb, indexRunFile))
+	if os.IsNotExist(err) {
+		return runs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func writeIndexRuns(kb string, runs []IndexRun) error {
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(kbPath(kb, indexRunFile), data)
+}
+
+// StartIndexRun 记录一次新的索引，保存每个输入文件的当前版本
+func StartIndexRun(kb string) (*IndexRun, error) {
+	inputs, err := CurrentInputVersions(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	indexRunLock.Lock()
+	defer indexRunLock.Unlock()
+
+	runs, err := ReadIndexRuns(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	id := now.Format("20060102-150405")
+	for i := 2; slices.ContainsFunc(runs, func(r IndexRun) bool { return r.ID == id }); i++ {
+		id = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), i)
+	}
+
+	run := IndexRun{
+		ID:        id,
+		StartedAt: now,
+		Status:    IndexRunRunning,
+		Inputs:    inputs,
+	}
+	if stat, err := os.Stat(kbPath(kb, "logs", indexLogFile)); err == nil {
+		run.LogOffset = stat.Size()
+	}
+	if err := writeIndexRuns(kb, append(runs, run)); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// archiveIndexRun 将 output 与 logs 复制到索引版本目录，索引日志只保留本次索引的部分
+func archiveIndexRun(kb string, run *IndexRun) error {
+	dst := kbPath(kb, indexVersionDir, run.ID)
+	tmp := kbPath(kb, indexVersionDir, "."+run.ID+".archiving")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{"output", "logs"} {
+		if _, err := os.Stat(kbPath(kb, dir)); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyDir(kbPath(kb, dir), filepath.Join(tmp, dir)); err != nil {
+			return err
+		}
+	}
+	if err := trimLogHead(filepath.Join(tmp, "logs", indexLogFile), run.LogOffset); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// trimLogHead 删除日志 offset 之前的内容，日志不存在或小于 offset（已被轮转）时不修改
+func trimLogHead(path string, offset int64) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if offset <= 0 || offset > stat.Size() {
+		return nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	return writeFileAtomicFrom(path, f)
+}
+
+// FinishIndexRun 记录索引结束，runErr 为 nil 时表示成功，成功时归档为索引版本
+func FinishIndexRun(kb string, run *IndexRun, runErr error) error {
+	id := run.ID
+	if runErr == nil {
+		if err := archiveIndexRun(kb, run); err != nil {
+			runErr = fmt.Errorf("failed to archive index version: %w", err)
+		}
+	}
+
+	indexRunLock.Lock()
+	defer indexRunLock.Unlock()
+
+	runs, err := ReadIndexRuns(kb)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(runs, func(r IndexRun) bool { return r.ID == id })
+	if i < 0 {
+		return fmt.Errorf("index run '%s' not exists", id)
+	}
+
+	now := time.Now()
+	runs[i].FinishedAt = &now
+	runs[i].Status = IndexRunSucceeded
+	if runErr != nil {
+		runs[i].Status = IndexRunFailed
+		runs[i].Error = runErr.Error()
+	} else {
+		if err := pruneIndexVersions(kb, runs); err != nil {
+			slog.Error("failed to prune index versions", slog.String("kb", kb), slog.String("err", err.Error()))
+		}
+		if err := pruneInputVersions(kb, runs); err != nil {
+			slog.Error("failed to prune input versions", slog.String("kb", kb), slog.String("err", err.Error()))
+		}
+	}
+	return writeIndexRuns(kb, runs)
+}
+
+// FailStaleIndexRuns 服务启动时没有正在进行的索引，将上次服务中断遗留的 running 记录标记为失败，返回标记数量
+func FailStaleIndexRuns() (int, error) {
+	kbs, err := ReadKB()
+	if err != nil {
+		return 0, err
+	}
+
+	indexRunLock.Lock()
+	defer indexRunLock.Unlock()
+
+	n := 0
+	for _, kb := range kbs {
+		runs, err := ReadIndexRuns(kb)
+		if err != nil {
+			return n, err
+		}
+		stale := 0
+		for i := range runs {
+			if runs[i].Status != IndexRunRunning {
+				continue
+			}
+			now := time.Now()
+			runs[i].FinishedAt = &now
+			runs[i].Status = IndexRunFailed
+			runs[i].Error = "interrupted by server restart"
+			stale++
+		}
+		if stale == 0 {
+			continue
+		}
+		if err := writeIndexRuns(kb, runs); err != nil {
+			return n, err
+		}
+		n += stale
+	}
+	return n, nil
+}
+
+// pruneIndexVersions 只保留最新的 global.IndexVersionRetention 个索引版本，删除的版本在 runs 中标记
+func pruneIndexVersions(kb string, runs []IndexRun) error {
+	if global.IndexVersionRetention <= 0 {
+		return nil
+	}
+	dbs, err := ReadData(kb)
+	if err != nil {
+		return err
+	}
+	// ReadData 返回当前 output 与按时间倒序的索引版本
+	versions := dbs[1:]
+	for _, id := range versions[min(global.IndexVersionRetention, len(versions)):] {
+		if err := os.RemoveAll(kbPath(kb, indexVersionDir, id)); err != nil {
+			return err
+		}
+		evictCaches(kb, id)
+		if i := slices.IndexFunc(runs, func(r IndexRun) bool { return r.ID == id }); i >= 0 {
+			runs[i].Pruned = true
+		}
+	}
+	return nil
+}
+
+// GetIndexRuns 获取知识库的索引记录
+func (ka *KBApi) GetIndexRuns(c *gin.Context) {
+	type GetIndexRunsReq struct {
+		KB string `json:"kb"`
+	}
+	type GetIndexRunsRsp struct {
+		BaseRsp
+		Runs []IndexRun `json:"runs"`
+	}
+
+	req := GetIndexRunsReq{}
+	rsp := GetIndexRunsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	runs, err := ReadIndexRuns(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Runs = runs
+	c.JSON(http.StatusOK, rsp)
+}
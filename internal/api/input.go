@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewLength = 2000
+	maxPreviewLength     = 100000
+)
+
+// InputFileInfo 输入文件详情
+type InputFileInfo struct {
+	Name            string    `json:"name"`
+	Size            int64     `json:"size"`
+	ModTime         time.Time `json:"mod_time"`
+	SHA256          string    `json:"sha256"`
+	Encoding        string    `json:"encoding,omitempty"`  // 文本编码，二进制文件为空
+	Converted       string    `json:"converted,omitempty"` // 转换得到的 txt 文件名
+	Chars           int       `json:"chars"`               // 字符数，二进制文件为转换后的字符数
+	EstimatedTokens int       `json:"estimated_tokens"`    // 估算的 token 数
+}
+
+// inputFile 获取输入文件的绝对路径，并检查文件是否存在
+func inputFile(kb, name string) (string, error) {
+	if err := checkKB(kb); err != nil {
+		return "", err
+	}
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	path := kbPath(kb, "input", name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("file '%s' not exists", name)
+	}
+	return path, nil
+}
+
+// countText 统计字符数并估算 token 数：汉字等 CJK 字符约 1 个 token，其余字符约 4 个一个 token
+func countText(r io.RuneReader) (chars, tokens int, err error) {
+	cjk, other := 0, 0
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		chars++
+		switch {
+		case unicode.Is(unicode.Han, c) || unicode.In(c, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case !unicode.IsSpace(c):
+			other++
+		}
+	}
+	return chars, cjk + (other+3)/4, nil
+}
+
+// inputTextPath 获取输入文件的文本路径。需要转换的文件（PDF、DOCX、HTML、Markdown）使用转换得到的 txt，
+// 即实际建立索引的文本
+func inputTextPath(path string) (string, error) {
+	if !needConvert(path) {
+		return path, nil
+	}
+	converted := filepath.Join(filepath.Dir(path), convertedName(filepath.Base(path)))
+	_, err := os.Stat(converted)
+	if err == nil {
+		return converted, nil
+	}
+	// 文本格式的文件没有转换结果时读取原文件
+	if _, binary := binaryMimeTypes[strings.ToLower(filepath.Ext(path))]; binary || !os.IsNotExist(err) {
+		return "", fmt.Errorf("converted text not found: %w", err)
+	}
+	return path, nil
+}
+
+// readInputText 读取输入文件的全部文本内容，非 UTF-8 文本按检测到的编码解码
+func readInputText(path string) (text, enc string, err error) {
+	path, err = inputTextPath(path)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	data = []byte(strings.TrimPrefix(string(data), string(utf8BOM)))
+
+	enc = detectEncoding(data)
+	switch enc {
+	case EncodingUTF8, EncodingUnknown:
+	default:
+		if data, err = encodingOf(enc).NewDecoder().Bytes(data); err != nil {
+			return "", enc, err
+		}
+	}
+	return string(data), enc, nil
+}
+
+// inputText 流式读取的输入文件文本
+type inputText struct {
+	*bufio.Reader
+	io.Closer
+	Encoding string
+	Size     int64 // 文本文件的字节数
+}
+
+// openInputText 打开输入文件的文本，读取时解码为 UTF-8。
+// 编码只根据开头 encodingChunkSize 字节检测，预览大文件时不必读取整个文件
+func openInputText(path string) (*inputText, error) {
+	path, err := inputTextPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r := bufio.NewReaderSize(f, encodingChunkSize)
+	if prefix, _ := r.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		r.Discard(len(utf8BOM))
+	}
+	prefix, err := r.Peek(encodingChunkSize)
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	if err == nil {
+		prefix = prefix[:utf8Boundary(prefix)]
+	}
+
+	text := inputText{Reader: r, Closer: f, Encoding: detectEncoding(prefix), Size: stat.Size()}
+	switch text.Encoding {
+	case EncodingUTF8, EncodingUnknown:
+	default:
+		text.Reader = bufio.NewReader(encodingOf(text.Encoding).NewDecoder().Reader(r))
+	}
+	return &text, nil
+}
+
+// ReadInputFileInfo 获取输入文件详情
+func ReadInputFileInfo(kb, name string) (*InputFileInfo, error) {
+	path, err := inputFile(kb, name)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := InputFileInfo{
+		Name:    name,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+		SHA256:  sum,
+	}
+	if needConvert(name) {
+		info.Converted = convertedName(name)
+	}
+
+	text, err := openInputText(path)
+	if err != nil {
+		// 转换失败的文件仍返回基本信息
+		return &info, nil
+	}
+	defer text.Close()
+	chars, tokens, err := countText(text)
+	if err != nil {
+		return nil, err
+	}
+	info.Encoding = text.Encoding
+	info.Chars = chars
+	info.EstimatedTokens = tokens
+	return &info, nil
+}
+
+// GetFileInfo 获取输入文件详情
+func (ka *KBApi) GetFileInfo(c *gin.Context) {
+	type GetFileInfoReq struct {
+		KB   string `json:"kb"`
+		File string `json:"file"`
+	}
+	type GetFileInfoRsp struct {
+		BaseRsp
+		Info *InputFileInfo `json:"info"`
+	}
+
+	req := GetFileInfoReq{}
+	rsp := GetFileInfoRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	info, err := ReadInputFileInfo(req.KB, req.File)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Info = info
+	c.JSON(http.StatusOK, rsp)
+}
+
+// PreviewFile 预览输入文件，返回从 start 开始的 length 个字符
+func (ka *KBApi) PreviewFile(c *gin.Context) {
+	type PreviewFileReq struct {
+		KB     string `json:"kb"`
+		File   string `json:"file"`
+		Start  int    `json:"start"`  // 起始字符位置，默认为 0
+		Length int    `json:"length"` // 字符数，默认为 2000
+	}
+	type PreviewFileRsp struct {
+		BaseRsp
+		Text  string `json:"text"`
+		Start int    `json:"start"`
+		End   int    `json:"end"`  // 结束字符位置（不含）
+		More  bool   `json:"more"` // end 之后是否还有内容
+		Size  int64  `json:"size"` // 文本文件的字节数，需要转换的文件为转换得到的 txt 的大小
+	}
+
+	req := PreviewFileReq{}
+	rsp := PreviewFileRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if req.Start < 0 || req.Length < 0 || req.Length > maxPreviewLength {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("start must not be negative and length must be in [0, %d]", maxPreviewLength)
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if req.Length == 0 {
+		req.Length = defaultPreviewLength
+	}
+
+	path, err := inputFile(req.KB, req.File)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	text, err := openInputText(path)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	defer text.Close()
+
+	// 逐个字符读取，只保留预览的部分
+	preview := strings.Builder{}
+	pos := 0
+	for ; pos < req.Start+req.Length; pos++ {
+		r, _, err := text.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		if pos >= req.Start {
+			preview.WriteRune(r)
+		}
+	}
+	if pos == req.Start+req.Length {
+		_, _, err = text.ReadRune()
+		rsp.More = err == nil
+	}
+	rsp.Start = min(req.Start, pos)
+	rsp.End = pos
+	rsp.Text = preview.String()
+	rsp.Size = text.Size
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// DownloadFile 下载输入文件原文，支持 HTTP Range
+// GET 参数：kb、file，inline=true 时在浏览器中直接打开
+func (ka *KBApi) DownloadFile(c *gin.Context) {
+	path, err := inputFile(c.Query("kb"), c.Query("file"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+
+	if c.Query("inline") == "true" {
+		c.File(path)
+		return
+	}
+	c.FileAttachment(path, filepath.Base(path))
+}
@@ -0,0 +1,581 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	inputVersionDir       = "input_versions"
+	inputVersionIndexFile = "versions.json"
+
+	// inputObjectDir 版本文件按 sha256 保存在 input_versions/.objects 下，各版本目录中的文件为其硬链接，
+	// 相同内容只保存一份。以 . 开头，不会与输入文件名冲突
+	inputObjectDir = ".objects"
+)
+
+// 输入文件版本来源
+const (
+	InputSourceInitial = "initial" // 启用版本记录前已有的文件
+	InputSourceUpload  = "upload"
+)
+
+// InputVersion 输入文件版本，保存上传的原文件与转换得到的文本
+type InputVersion struct {
+	Version    string    `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Source     string    `json:"source"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	TextSHA256 string    `json:"text_sha256,omitempty"` // 转换得到的文本的 sha256
+}
+
+// inputHistory 单个输入文件的版本记录
+type inputHistory struct {
+	Current  string         `json:"current"`
+	Versions []InputVersion `json:"versions"`
+}
+
+func inputVersionPath(kb, name string, elem ...string) string {
+	return kbPath(kb, append([]string{inputVersionDir, name}, elem...)...)
+}
+
+func inputObjectPath(kb, sum string) string {
+	return kbPath(kb, inputVersionDir, inputObjectDir, sum)
+}
+
+// storeInputObject 按 sha256 保存文件内容并链接到 dst，内容已保存过时不再复制，需持有 commitLock
+// 文件系统不支持硬链接时复制到 dst
+func storeInputObject(kb, src, dst string) (string, error) {
+	sum, err := fileSHA256(src)
+	if err != nil {
+		return "", err
+	}
+	obj := inputObjectPath(kb, sum)
+	if _, err := os.Stat(obj); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(obj), os.ModePerm); err != nil {
+			return "", err
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if err := writeFileAtomicFrom(obj, f); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	if err := os.Link(obj, dst); err != nil {
+		if err := copyFile(obj, dst); err != nil {
+			return "", err
+		}
+	}
+	return sum, nil
+}
+
+func readInputHistory(kb, name string) (*inputHistory, error) {
+	history := inputHistory{}
+	data, err := os.ReadFile(inputVersionPath(kb, name, inputVersionIndexFile))
+	if os.IsNotExist(err) {
+		return &history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+func writeInputHistory(kb, name string, history *inputHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(inputVersionPath(kb, name, inputVersionIndexFile), data)
+}
+
+func (h *inputHistory) find(version string) *InputVersion {
+	i := slices.IndexFunc(h.Versions, func(v InputVersion) bool { return v.Version == version })
+	if i < 0 {
+		return nil
+	}
+	return &h.Versions[i]
+}
+
+// newInputVersion 将原文件 src 与转换得到的文本 text（可为空）保存为新版本，需持有 commitLock
+func newInputVersion(kb, name string, history *inputHistory, src, text, source string) (*InputVersion, error) {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	version := now.Format("20060102-150405")
+	for i := 2; history.find(version) != nil; i++ {
+		version = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), i)
+	}
+
+	dir := inputVersionPath(kb, name, version)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	v := InputVersion{
+		Version:   version,
+		CreatedAt: now,
+		Source:    source,
+		Size:      stat.Size(),
+	}
+	if v.SHA256, err = storeInputObject(kb, src, filepath.Join(dir, name)); err != nil {
+		return nil, err
+	}
+	if text != "" {
+		if v.TextSHA256, err = storeInputObject(kb, text, filepath.Join(dir, convertedName(name))); err != nil {
+			return nil, err
+		}
+	}
+
+	history.Versions = append(history.Versions, v)
+	return &v, nil
+}
+
+// snapshotInput 将 input 目录中的当前文件记录为初始版本，清洗过的文本使用清洗前的原始文本，需持有 commitLock
+func snapshotInput(kb, name string, history *inputHistory) error {
+	src := kbPath(kb, "input", name)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	text := ""
+	if needConvert(name) {
+		text = kbPath(kb, "input", convertedName(name))
+	}
+	if target := cleanTarget(name); target != "" {
+		if _, err := os.Stat(kbPath(kb, rawDir, target)); err == nil {
+			if target == name {
+				src = kbPath(kb, rawDir, target)
+			} else {
+				text = kbPath(kb, rawDir, target)
+			}
+		}
+	}
+
+	v, err := newInputVersion(kb, name, history, src, text, InputSourceInitial)
+	if err != nil {
+		return err
+	}
+	history.Current = v.Version
+	return nil
+}
+
+// saveInputVersion 提交上传前将暂存的文件保存为新版本，需持有 commitLock
+// 首次保存时会将已有的同名文件记录为初始版本；内容与当前版本相同时不新建版本
+func saveInputVersion(kb, name, staged, text string) (*InputVersion, error) {
+	history, err := readInputHistory(kb, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(history.Versions) == 0 {
+		if err := snapshotInput(kb, name, history); err != nil {
+			return nil, err
+		}
+	}
+
+	sum, err := fileSHA256(staged)
+	if err != nil {
+		return nil, err
+	}
+	if current := history.find(history.Current); current != nil && current.SHA256 == sum {
+		return current, writeInputHistory(kb, name, history)
+	}
+
+	v, err := newInputVersion(kb, name, history, staged, text, InputSourceUpload)
+	if err != nil {
+		return nil, err
+	}
+	history.Current = v.Version
+	if err := writeInputHistory(kb, name, history); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ReadInputVersions 获取输入文件的版本记录，尚未记录版本的已有文件返回空列表
+func ReadInputVersions(kb, name string) (string, []InputVersion, error) {
+	if err := checkName(name); err != nil {
+		return "", nil, err
+	}
+	history, err := readInputHistory(kb, name)
+	if err != nil {
+		return "", nil, err
+	}
+	if history.Versions == nil {
+		history.Versions = []InputVersion{}
+	}
+	return history.Current, history.Versions, nil
+}
+
+// ReadInputVersionText 获取输入文件指定版本的文本，version 为空时返回 input 目录中的当前文本
+func ReadInputVersionText(kb, name, version string) (string, error) {
+	if version == "" {
+		path, err := inputFile(kb, name)
+		if err != nil {
+			return "", err
+		}
+		text, _, err := readInputText(path)
+		return text, err
+	}
+
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	if err := checkName(version); err != nil {
+		return "", err
+	}
+	path := inputVersionPath(kb, name, version, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("file '%s' version '%s' not exists", name, version)
+	}
+	// 版本目录与 input 目录结构相同，转换得到的文本在原文件旁边
+	text, _, err := readInputText(path)
+	return text, err
+}
+
+// RestoreInputVersion 将指定版本恢复到 input 目录并设为当前版本，返回清洗失败等警告
+func RestoreInputVersion(kb, name, version string) ([]string, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+
+	commitLock.Lock()
+	defer commitLock.Unlock()
+
+	history, err := readInputHistory(kb, name)
+	if err != nil {
+		return nil, err
+	}
+	if history.find(version) == nil {
+		return nil, fmt.Errorf("file '%s' version '%s' not exists", name, version)
+	}
+
+	// 先复制到 input 目录中的隐藏文件再重命名，避免恢复中断时留下不完整的文件
+	restore := func(file string) error {
+		tmp := kbPath(kb, "input", "."+file+".restoring")
+		defer os.Remove(tmp)
+		if err := copyFile(inputVersionPath(kb, name, version, file), tmp); err != nil {
+			return err
+		}
+		return os.Rename(tmp, kbPath(kb, "input", file))
+	}
+	if err := os.MkdirAll(kbPath(kb, "input"), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if needConvert(name) {
+		if err := restore(convertedName(name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := restore(name); err != nil {
+		return nil, err
+	}
+
+	if err := updateInputLinks(kb, name, ""); err != nil {
+		return nil, err
+	}
+	history.Current = version
+	if err := writeInputHistory(kb, name, history); err != nil {
+		return nil, err
+	}
+
+	// 与上传相同，旧的原始文本不再有效，上传时清洗的知识库重新清洗
+	warnings := []string{}
+	target := cleanTarget(name)
+	if target == "" {
+		return warnings, nil
+	}
+	if err := removeRaw(kb, target); err != nil {
+		return nil, err
+	}
+	opts, err := ReadKBOptions(kb)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Clean.Mode == CleanUpload {
+		if _, err := CleanInput(kb, target, opts.Clean.Steps); err != nil {
+			warnings = append(warnings, fmt.Sprintf("清洗失败: %s", err.Error()))
+		}
+	}
+	return warnings, nil
+}
+
+// CurrentInputVersions 获取所有输入文件的当前版本，key 为文件名，尚未记录版本的文件先记录为初始版本
+func CurrentInputVersions(kb string) (map[string]string, error) {
+	files, err := ReadInput(kb)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	commitLock.Lock()
+	defer commitLock.Unlock()
+
+	versions := map[string]string{}
+	for _, file := range files {
+		if isConverted(kb, file) {
+			continue
+		}
+		history, err := readInputHistory(kb, file)
+		if err != nil {
+			return nil, err
+		}
+		if len(history.Versions) == 0 {
+			if err := snapshotInput(kb, file, history); err != nil {
+				return nil, err
+			}
+			if err := writeInputHistory(kb, file, history); err != nil {
+				return nil, err
+			}
+		}
+		versions[file] = history.Current
+	}
+	return versions, nil
+}
+
+// pruneInputVersions 删除既不是当前版本、也没有被保留的索引记录（未删除版本且未失败）使用的输入文件版本，
+// 并删除不再被任何版本引用的文件内容
+func pruneInputVersions(kb string, runs []IndexRun) error {
+	used := map[string]map[string]bool{}
+	for _, run := range runs {
+		if run.Pruned || run.Status == IndexRunFailed {
+			continue
+		}
+		for file, version := range run.Inputs {
+			if used[file] == nil {
+				used[file] = map[string]bool{}
+			}
+			used[file][version] = true
+		}
+	}
+
+	commitLock.Lock()
+	defer commitLock.Unlock()
+
+	files, err := os.ReadDir(kbPath(kb, inputVersionDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	objects := map[string]bool{}
+	for _, file := range files {
+		name := file.Name()
+		if !file.IsDir() || checkName(name) != nil {
+			continue
+		}
+		history, err := readInputHistory(kb, name)
+		if err != nil {
+			return err
+		}
+		versions := history.Versions[:0]
+		for _, v := range history.Versions {
+			if v.Version == history.Current || used[name][v.Version] {
+				versions = append(versions, v)
+				objects[v.SHA256], objects[v.TextSHA256] = true, true
+				continue
+			}
+			if err := os.RemoveAll(inputVersionPath(kb, name, v.Version)); err != nil {
+				return err
+			}
+		}
+		if len(versions) == len(history.Versions) {
+			continue
+		}
+		history.Versions = versions
+		if err := writeInputHistory(kb, name, history); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(kbPath(kb, inputVersionDir, inputObjectDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if objects[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if err := os.Remove(inputObjectPath(kb, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFileVersions 获取输入文件的版本列表
+func (ka *KBApi) GetFileVersions(c *gin.Context) {
+	type GetFileVersionsReq struct {
+		KB   string `json:"kb"`
+		File string `json:"file"`
+	}
+	type GetFileVersionsRsp struct {
+		BaseRsp
+		Current  string         `json:"current"`
+		Versions []InputVersion `json:"versions"`
+	}
+
+	req := GetFileVersionsReq{}
+	rsp := GetFileVersionsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	current, versions, err := ReadInputVersions(req.KB, req.File)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Current = current
+	rsp.Versions = versions
+	c.JSON(http.StatusOK, rsp)
+}
+
+// DiffFile 比较输入文件两个版本的文本，版本为空时表示 input 目录中的当前文本
+func (ka *KBApi) DiffFile(c *gin.Context) {
+	type DiffFileReq struct {
+		KB   string `json:"kb"`
+		File string `json:"file"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	type DiffFileRsp struct {
+		BaseRsp
+		Diff string `json:"diff"`
+	}
+
+	req := DiffFileReq{}
+	rsp := DiffFileRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	from, err := ReadInputVersionText(req.KB, req.File, req.From)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	to, err := ReadInputVersionText(req.KB, req.File, req.To)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	versionName := func(version string) string {
+		if version == "" {
+			return req.File + " (current)"
+		}
+		return req.File + "@" + version
+	}
+
+	diff, err := unifiedDiff(versionName(req.From), versionName(req.To), from, to, 3)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Diff = diff
+	c.JSON(http.StatusOK, rsp)
+}
+
+// RestoreFile 将输入文件恢复到指定版本
+func (ka *KBApi) RestoreFile(c *gin.Context) {
+	type RestoreFileReq struct {
+		KB      string `json:"kb"`
+		File    string `json:"file"`
+		Version string `json:"version"`
+	}
+	type RestoreFileRsp struct {
+		BaseRsp
+		Warnings []string `json:"warnings,omitempty"`
+	}
+
+	req := RestoreFileReq{}
+	rsp := RestoreFileRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	warnings, err := RestoreInputVersion(req.KB, req.File, req.Version)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Warnings = warnings
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	jobLogLines  = 200            // 每个任务保留的日志行数
+	jobRetention = 24 * time.Hour // 已结束任务的保留时间
+)
+
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// JobInfo 后台任务状态
+type JobInfo struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	KB         string     `json:"kb"`
+	Status     JobStatus  `json:"status"`
+	Progress   float64    `json:"progress"` // 0 ~ 1
+	Message    string     `json:"message"`
+	Error      string     `json:"error,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	Logs       []string   `json:"logs,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job 后台任务
+type Job struct {
+	JobInfo
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// SetProgress 更新任务进度
+func (j *Job) SetProgress(progress float64, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Progress = progress
+	j.Message = message
+}
+
+// Log 记录任务日志，仅保留最近的 jobLogLines 行
+func (j *Job) Log(line string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Logs = append(j.Logs, line)
+	if len(j.Logs) > jobLogLines {
+		j.Logs = j.Logs[len(j.Logs)-jobLogLines:]
+	}
+}
+
+// Info 获取任务当前状态的副本
+func (j *Job) Info(withLogs bool) JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := j.JobInfo
+	info.Logs = nil
+	if withLogs {
+		info.Logs = append([]string{}, j.Logs...)
+	}
+	return info
+}
+
+type jobManager struct {
+	mu   sync.Mutex
+	seq  int
+	jobs map[string]*Job
+}
+
+var jobs = &jobManager{jobs: map[string]*Job{}}
+
+// StartJob 启动后台任务，同一知识库同一类型的任务同时只能运行一个
+func StartJob(jobType, kb string, run func(ctx context.Context, job *Job) (any, error)) (*Job, error) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	now := time.Now()
+	for id, j := range jobs.jobs {
+		s := j.Info(false)
+		if s.Type == jobType && s.KB == kb && s.Status == JobRunning {
+			return nil, fmt.Errorf("job '%s' of kb '%s' is already running: %s", jobType, kb, id)
+		}
+		if s.FinishedAt != nil && now.Sub(*s.FinishedAt) > jobRetention {
+			delete(jobs.jobs, id)
+		}
+	}
+
+	jobs.seq++
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		JobInfo: JobInfo{
+			ID:        fmt.Sprintf("%s-%d", now.Format("20060102-150405"), jobs.seq),
+			Type:      jobType,
+			KB:        kb,
+			Status:    JobRunning,
+			CreatedAt: now,
+		},
+		cancel: cancel,
+	}
+	jobs.jobs[job.ID] = job
+
+	go func() {
+		defer cancel()
+		result, err := run(ctx, job)
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		finished := time.Now()
+		job.FinishedAt = &finished
+		job.Result = result
+		switch {
+		case ctx.Err() != nil:
+			job.Status = JobCanceled
+			job.Error = ctx.Err().Error()
+		case err != nil:
+			job.Status = JobFailed
+			job.Error = err.Error()
+			slog.Error("job failed",
+				slog.String("id", job.ID),
+				slog.String("type", job.Type),
+				slog.String("err", err.Error()))
+		default:
+			job.Status = JobSucceeded
+			job.Progress = 1
+		}
+	}()
+
+	return job, nil
+}
+
+// GetJob 获取任务
+func GetJob(id string) (*Job, bool) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	job, ok := jobs.jobs[id]
+	return job, ok
+}
+
+type JobApi struct {
+}
+
+func (ja *JobApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/job")
+
+	r.POST("", ja.GetJobs)
+	r.POST("/get", ja.GetJob)
+	r.POST("/cancel", ja.CancelJob)
+}
+
+// GetJobs 获取任务列表，可按知识库与类型过滤
+func (ja *JobApi) GetJobs(c *gin.Context) {
+	type GetJobsReq struct {
+		KB   string `json:"kb"`
+		Type string `json:"type"`
+	}
+	type GetJobsRsp struct {
+		BaseRsp
+		Jobs []JobInfo `json:"jobs"`
+	}
+
+	req := GetJobsReq{}
+	rsp := GetJobsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	jobs.mu.Lock()
+	list := []JobInfo{}
+	for _, j := range jobs.jobs {
+		s := j.Info(false)
+		if (req.KB == "" || s.KB == req.KB) && (req.Type == "" || s.Type == req.Type) {
+			list = append(list, s)
+		}
+	}
+	jobs.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Jobs = list
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetJob 获取任务详情及日志
+func (ja *JobApi) GetJob(c *gin.Context) {
+	type GetJobReq struct {
+		ID string `json:"id"`
+	}
+	type GetJobRsp struct {
+		BaseRsp
+		Job *JobInfo `json:"job"`
+	}
+
+	req := GetJobReq{}
+	rsp := GetJobRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	job, ok := GetJob(req.ID)
+	if !ok {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("job '%s' not exists", req.ID)
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	s := job.Info(true)
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Job = &s
+	c.JSON(http.StatusOK, rsp)
+}
+
+// CancelJob 取消任务
+func (ja *JobApi) CancelJob(c *gin.Context) {
+	type CancelJobReq struct {
+		ID string `json:"id"`
+	}
+	type CancelJobRsp struct {
+		BaseRsp
+	}
+
+	req := CancelJobReq{}
+	rsp := CancelJobRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	job, ok := GetJob(req.ID)
+	if !ok {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("job '%s' not exists", req.ID)
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	job.cancel()
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// runJobCommand 执行命令并将标准输出与标准错误逐行写入任务日志
+func runJobCommand(job *Job, cmd *exec.Cmd) error {
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			job.Log(scanner.Text())
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Wait()
+	pw.Close()
+	<-done
+	return err
+}
@@ -2,12 +2,15 @@ package api
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"graphraggo/internal/global"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -24,14 +27,32 @@ func (ka *KBApi) Register(rg *gin.RouterGroup) {
 	r.POST("/add", ka.AddKB)
 	r.POST("/delete", ka.DeleteKB)
 	r.POST("/indexing", ka.IndexKB)
+	r.POST("/runs", ka.GetIndexRuns)
 	r.POST("/file/upload", ka.UploadFile)
 	r.POST("/file/delete", ka.DeleteFile)
+	r.POST("/file/info", ka.GetFileInfo)
+	r.POST("/file/preview", ka.PreviewFile)
+	r.GET("/file/download", ka.DownloadFile)
+	r.POST("/file/duplicates", ka.GetDuplicates)
+	r.POST("/file/versions", ka.GetFileVersions)
+	r.POST("/file/diff", ka.DiffFile)
+	r.POST("/file/restore", ka.RestoreFile)
+	r.POST("/csv/columns", ka.GetCSVColumns)
+	r.POST("/csv/mapping", ka.UpdateCSVMapping)
+	r.POST("/import/archive", ka.ImportArchive)
+	r.POST("/import/dir", ka.ImportDir)
+	r.POST("/clean/preview", ka.PreviewClean)
+	r.POST("/metadata", ka.GetMetadata)
+	r.POST("/metadata/update", ka.UpdateMetadata)
+	r.POST("/options", ka.GetOptions)
+	r.POST("/options/update", ka.UpdateOptions)
 }
 
-// AddKB 新建知识库
+// AddKB 新建知识库，可指定模板，不指定时使用示例配置与提示词
 func (ka *KBApi) AddKB(c *gin.Context) {
 	type AddKBReq struct {
-		Name string `json:"name"`
+		Name     string `json:"name"`
+		Template string `json:"template"`
 	}
 	type AddKBRsp struct {
 		BaseRsp
@@ -46,6 +67,21 @@ func (ka *KBApi) AddKB(c *gin.Context) {
 		return
 	}
 
+	if err := checkName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if req.Template != "" {
+		if err := checkTemplate(req.Template); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+	}
+
 	// 判断文件夹是否存在
 	path := fmt.Sprintf("%s/%s/%s", global.WorkDir, global.KBDir, req.Name)
 	_, err := os.Stat(path)
@@ -66,8 +102,35 @@ func (ka *KBApi) AddKB(c *gin.Context) {
 
 	os.Mkdir(path, os.ModePerm)
 	os.Mkdir(path+"/input", os.ModePerm)
+
+	// 使用模板
+	if req.Template != "" {
+		if err := applyTemplate(req.Template, req.Name); err != nil {
+			os.RemoveAll(path)
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+
+		rsp.Code = 0
+		rsp.Msg = "success"
+		c.JSON(http.StatusOK, rsp)
+		return
+	}
+
 	cmd := exec.Command("cp", global.ExampleSettingFile, path+"/settings.yaml")
 	if err := cmd.Run(); err != nil {
+		os.RemoveAll(path)
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	// 复制示例提示词
+	if err := copyDir(global.ExamplePromptDir, path+"/"+promptDir); err != nil {
+		os.RemoveAll(path)
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
@@ -79,7 +142,7 @@ func (ka *KBApi) AddKB(c *gin.Context) {
 	c.JSON(http.StatusOK, rsp)
 }
 
-// DeleteKB 删除知识库
+// DeleteKB 删除知识库，移入回收站
 func (ka *KBApi) DeleteKB(c *gin.Context) {
 	type DeleteKBReq struct {
 		Name string `json:"name"`
@@ -103,6 +166,12 @@ func (ka *KBApi) DeleteKB(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, rsp)
 		return
 	}
+	if err := checkName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
 
 	// 判断文件夹是否存在
 	path := fmt.Sprintf("%s/%s/%s", global.WorkDir, global.KBDir, req.Name)
@@ -115,12 +184,14 @@ func (ka *KBApi) DeleteKB(c *gin.Context) {
 		return
 	}
 
-	if err := os.RemoveAll(path); err != nil {
+	// 移入回收站
+	if _, err := MoveToTrash(TrashKB, req.Name, req.Name, path, operator(c)); err != nil {
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
 		return
 	}
+	evictCaches(req.Name, "")
 
 	rsp.Code = 0
 	rsp.Msg = "success"
@@ -180,6 +251,7 @@ func (ka *KBApi) IndexKB(c *gin.Context) {
 	}
 	type IndexingKBRsp struct {
 		BaseRsp
+		Issues []CSVIssue `json:"issues,omitempty"`
 	}
 
 	req := IndexingKBReq{}
@@ -191,9 +263,40 @@ func (ka *KBApi) IndexKB(c *gin.Context) {
 		return
 	}
 
+	if err := checkKB(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
 	path := fmt.Sprintf("%s/%s/%s", global.WorkDir, global.KBDir, req.Name)
 
-	// 创建文件记录状态
+	// CSV 模式下先校验每行文本非空，避免索引到一半才失败
+	cfg, err := ReadSettings(req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if m := csvMappingOf(cfg); m != nil {
+		issues, err := ValidateCSVInputs(req.Name, *m)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		if len(issues) > 0 {
+			rsp.Code = -1
+			rsp.Msg = "csv validation failed"
+			rsp.Issues = issues
+			c.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+	}
+
+	// 加锁后再清洗与生成输入，避免修改正在进行的索引使用的文件
 	if !lock.TryLock() {
 		// 加锁失败
 		rsp.Code = -1
@@ -204,11 +307,49 @@ func (ka *KBApi) IndexKB(c *gin.Context) {
 
 	defer lock.Unlock() // 释放锁
 
-	cmd := exec.CommandContext(c, global.PythonPath,
-		"-m", "graphrag index",
-		"--root", path)
+	// 建立索引前清洗
+	opts, err := ReadKBOptions(req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if opts.Clean.Mode == CleanIndex {
+		if _, err := CleanInputs(req.Name); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+	}
+
+	// 有元数据时生成带元数据列的 CSV 输入，graphrag 使用生成的配置文件
+	config, err := PrepareMetadataInput(req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	args := []string{"-m", "graphrag", "index", "--root", path}
+	if config != "" {
+		args = append(args, "--config", config)
+	}
+
+	// 记录本次索引使用的输入文件版本
+	run, err := StartIndexRun(req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	cmd := exec.CommandContext(c, global.PythonPath, args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		FinishIndexRun(req.Name, run, err)
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
@@ -216,6 +357,7 @@ func (ka *KBApi) IndexKB(c *gin.Context) {
 	}
 
 	if err := cmd.Start(); err != nil {
+		FinishIndexRun(req.Name, run, err)
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
@@ -240,7 +382,9 @@ func (ka *KBApi) IndexKB(c *gin.Context) {
 	}
 
 	// 等待命令执行完毕
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	FinishIndexRun(req.Name, run, err)
+	if err != nil {
 		rsp.Code = -1
 		rsp.Msg = err.Error()
 		c.JSON(http.StatusInternalServerError, rsp)
@@ -264,7 +408,8 @@ func ReadInput(kb string) ([]string, error) {
 
 	outputs := []string{}
 	for _, file := range files {
-		if file.Type().IsDir() {
+		// 跳过文件夹与上传中的临时文件
+		if file.Type().IsDir() || strings.HasPrefix(file.Name(), ".") {
 			continue
 		}
 		outputs = append(outputs, file.Name())
@@ -307,60 +452,262 @@ func (da *KBApi) GetInput(c *gin.Context) {
 	c.JSON(http.StatusOK, rsp)
 }
 
-// UploadFile 文件上传
+// UploadFileResult 单个文件的上传结果
+type UploadFileResult struct {
+	Name       string           `json:"name"`
+	Code       int              `json:"code"`
+	Msg        string           `json:"msg"`
+	Detection  *UploadDetection `json:"detection,omitempty"`
+	Conversion *ConvertResult   `json:"conversion,omitempty"`
+	Duplicate  *DuplicateInfo   `json:"duplicate,omitempty"`
+	Version    string           `json:"version,omitempty"` // 保存的文件版本
+	Warnings   []string         `json:"warnings,omitempty"`
+}
+
+// commitLock 保证去重检查与提交之间没有其他上传
+var commitLock sync.Mutex
+
+// CommitUpload 检测已保存到临时文件的上传文件，通过后原子地移动到知识库 input 目录并转换文档
+// tmp 必须与 input 目录位于同一文件系统，成功后 tmp 已被移动，失败时 tmp 保留（可能已转码）以便重试
+func CommitUpload(kb, filename, tmp string) (_ *UploadFileResult, _ int, err error) {
+	result := UploadFileResult{Name: filename}
+
+	// 检测文件类型与编码，GBK、GB18030、BIG5 转为 UTF-8
+	detection, err := InspectUpload(tmp, filename)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	result.Detection = detection
+
+	path := kbPath(kb, "input")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	// 在暂存目录中转换并计算内容哈希，确认不重复后再移动到 input 目录
+	staging, err := os.MkdirTemp(path, ".staging-*")
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	staged := filepath.Join(staging, filename)
+	defer func() {
+		if err != nil {
+			os.Rename(staged, tmp)
+		}
+		os.RemoveAll(staging)
+	}()
+	if err := os.Rename(tmp, staged); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// PDF、DOCX、HTML、Markdown 转换为 txt，原文件保留在旁边
+	if needConvert(filename) {
+		conversion, err := ConvertDocument(staged)
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("文件转换失败: %w", err)
+		}
+		result.Conversion = conversion
+	}
+
+	hash, err := fileContentHash(staged)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	commitLock.Lock()
+	defer commitLock.Unlock()
+
+	opts, err := ReadKBOptions(kb)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	original, err := findDuplicate(kb, filename, hash)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if original != "" {
+		result.Duplicate = &DuplicateInfo{Of: original, Policy: opts.Upload.Duplicate}
+		switch opts.Upload.Duplicate {
+		case DuplicateReject:
+			return nil, http.StatusConflict, fmt.Errorf("文件内容与 %s 重复", original)
+		case DuplicateLink:
+			if err := updateInputLinks(kb, filename, original); err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			result.Msg = fmt.Sprintf("文件内容与 %s 重复，已记录为链接", original)
+			return &result, http.StatusOK, nil
+		default:
+			result.Warnings = append(result.Warnings, fmt.Sprintf("文件内容与 %s 重复", original))
+		}
+	}
+
+	// 同名文件上传为新版本，旧版本保留在 input_versions 目录
+	text := ""
+	if result.Conversion != nil {
+		text = filepath.Join(staging, result.Conversion.Output)
+	}
+	version, err := saveInputVersion(kb, filename, staged, text)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	result.Version = version.Version
+
+	// 先移动转换得到的 txt，再移动原文件
+	if result.Conversion != nil {
+		if err := os.Rename(filepath.Join(staging, result.Conversion.Output),
+			filepath.Join(path, result.Conversion.Output)); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+	if err := os.Rename(staged, filepath.Join(path, filename)); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// 重新上传了同名文件，不再是链接，旧的原始文本也不再有效
+	if err := updateInputLinks(kb, filename, ""); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	target := cleanTarget(filename)
+	if target != "" {
+		if err := removeRaw(kb, target); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	// 上传时清洗，失败时保留未清洗的文本
+	if target != "" && opts.Clean.Mode == CleanUpload {
+		if _, err := CleanInput(kb, target, opts.Clean.Steps); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("清洗失败: %s", err.Error()))
+		}
+	}
+
+	result.Msg = "文件上传成功"
+	return &result, http.StatusOK, nil
+}
+
+// checkUpload 上传前检查文件名、文件类型与大小
+func checkUpload(kb, filename string, size int64) (int, error) {
+	if err := checkName(filename); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	opts, err := ReadKBOptions(kb)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !opts.AllowExtension(filename) {
+		return http.StatusBadRequest, fmt.Errorf("文件类型不允许上传: %s", filename)
+	}
+
+	// 覆盖同名文件时不计入原文件大小
+	var exists int64
+	if info, err := os.Stat(kbPath(kb, "input", filename)); err == nil {
+		exists = info.Size()
+	}
+	if err := checkUploadSize(opts, kbPath(kb, "input"), size, exists); err != nil {
+		return http.StatusRequestEntityTooLarge, err
+	}
+	return http.StatusOK, nil
+}
+
+// uploadFile 保存并提交 multipart 表单中的单个文件
+func uploadFile(c *gin.Context, kb string, file *multipart.FileHeader) (*UploadFileResult, int, error) {
+	if status, err := checkUpload(kb, file.Filename, file.Size); err != nil {
+		return nil, status, err
+	}
+
+	path := kbPath(kb, "input")
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	// 先保存到临时文件，检测通过后再覆盖，避免不合格的文件覆盖已有文件
+	tmp := filepath.Join(path, "."+file.Filename+".uploading")
+	if err := c.SaveUploadedFile(file, tmp); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	result, status, err := CommitUpload(kb, file.Filename, tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return nil, status, err
+	}
+	return result, status, nil
+}
+
+// UploadFile 文件上传，支持一次上传多个 file 字段，分别返回每个文件的结果
+// 大文件请使用 /upload 下的断点续传接口
 func (da *KBApi) UploadFile(c *gin.Context) {
 	type UploadFileRsp struct {
-		Code int    `json:"code"`
-		Msg  string `json:"msg"`
+		BaseRsp
+		Files []UploadFileResult `json:"files"`
 	}
 
-	// 这里不再使用 ShouldBindJSON，因为我们需要接收的是 multipart/form-data 类型的数据
-	kb := c.DefaultPostForm("kb", "") // 获取表单字段 "kb"（默认值为空字符串）
+	rsp := UploadFileRsp{}
 
-	// 获取上传的文件
-	file, err := c.FormFile("file")
+	// 这里不再使用 ShouldBindJSON，因为我们需要接收的是 multipart/form-data 类型的数据
+	form, err := c.MultipartForm()
 	if err != nil {
-		rsp := UploadFileRsp{
-			Code: -1,
-			Msg:  err.Error(),
-		}
+		rsp.Code = -1
+		rsp.Msg = err.Error()
 		c.JSON(http.StatusBadRequest, rsp)
 		return
 	}
 
-	// 确定文件保存路径
-	path := fmt.Sprintf("%s/%s/%s/input", global.WorkDir, global.KBDir, kb)
-	dst := filepath.Join(path, file.Filename)
+	kb := ""
+	if values := form.Value["kb"]; len(values) > 0 {
+		kb = values[0]
+	}
+	if err := checkKB(kb); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
 
-	// 创建文件目录（如果不存在）
-	if err := os.MkdirAll(path, os.ModePerm); err != nil {
-		rsp := UploadFileRsp{
-			Code: -1,
-			Msg:  "创建目录失败: " + err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, rsp)
+	files := form.File["file"]
+	if len(files) == 0 {
+		rsp.Code = -1
+		rsp.Msg = "no file uploaded"
+		c.JSON(http.StatusBadRequest, rsp)
 		return
 	}
 
-	// 保存文件到本地
-	if err := c.SaveUploadedFile(file, dst); err != nil {
-		rsp := UploadFileRsp{
-			Code: -1,
-			Msg:  err.Error(),
+	// 逐个上传，单个文件失败不影响其他文件
+	rsp.Files = []UploadFileResult{}
+	status := http.StatusOK
+	failed := 0
+	for _, file := range files {
+		result, code, err := uploadFile(c, kb, file)
+		if err != nil {
+			failed++
+			status = code
+			result = &UploadFileResult{
+				Name: file.Filename,
+				Code: -1,
+				Msg:  err.Error(),
+			}
 		}
-		c.JSON(http.StatusInternalServerError, rsp)
-		return
+		rsp.Files = append(rsp.Files, *result)
 	}
 
-	// 返回成功响应
-	rsp := UploadFileRsp{
-		Code: 0,
-		Msg:  "文件上传成功",
+	switch {
+	case failed == 0:
+		rsp.Code = 0
+		rsp.Msg = "文件上传成功"
+	case failed == len(files):
+		rsp.Code = -1
+		rsp.Msg = "文件上传失败"
+	default:
+		// 部分成功
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("%d 个文件上传失败", failed)
+		status = http.StatusMultiStatus
 	}
-	c.JSON(http.StatusOK, rsp)
+	c.JSON(status, rsp)
 }
 
-// 删除文件
+// DeleteFile 删除文件，移入回收站
 func (da *KBApi) DeleteFile(c *gin.Context) {
 	type DeleteFileReq struct {
 		KB    string   `json:"kb"`
@@ -379,15 +726,169 @@ func (da *KBApi) DeleteFile(c *gin.Context) {
 		return
 	}
 
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
 	for _, file := range req.Files {
+		if err := checkName(file); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+
+		// 重复上传时记录的链接没有对应的文件，直接删除链接
+		if err := updateInputLinks(req.KB, file, ""); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+
+		// 移入回收站
 		path := fmt.Sprintf("%s/%s/%s/input/%s", global.WorkDir, global.KBDir, req.KB, file)
-		err := os.Remove(path)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		_, err := MoveToTrash(TrashInput, req.KB, file, path, operator(c))
 		if err != nil {
 			rsp.Code = -1
 			rsp.Msg = err.Error()
 			c.JSON(http.StatusInternalServerError, rsp)
 			return
 		}
+
+		// 同时删除清洗前的原始文本
+		if target := cleanTarget(file); target != "" {
+			raw := kbPath(req.KB, rawDir, target)
+			if _, err := os.Stat(raw); err == nil {
+				if _, err := MoveToTrash(TrashInput, req.KB, rawDir+"/"+target, raw, operator(c)); err != nil {
+					rsp.Code = -1
+					rsp.Msg = err.Error()
+					c.JSON(http.StatusInternalServerError, rsp)
+					return
+				}
+			}
+		}
+
+		// 同时删除转换得到的 txt
+		if needConvert(file) {
+			derived := fmt.Sprintf("%s/%s/%s/input/%s", global.WorkDir, global.KBDir, req.KB, convertedName(file))
+			if _, err := os.Stat(derived); err == nil {
+				if _, err := MoveToTrash(TrashInput, req.KB, convertedName(file), derived, operator(c)); err != nil {
+					rsp.Code = -1
+					rsp.Msg = err.Error()
+					c.JSON(http.StatusInternalServerError, rsp)
+					return
+				}
+			}
+		}
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetOptions 获取知识库导入选项
+func (ka *KBApi) GetOptions(c *gin.Context) {
+	type GetOptionsReq struct {
+		KB string `json:"kb"`
+	}
+	type GetOptionsRsp struct {
+		BaseRsp
+		Options *KBOptions `json:"options"`
+	}
+
+	req := GetOptionsReq{}
+	rsp := GetOptionsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	opts, err := ReadKBOptions(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Options = opts
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UpdateOptions 修改知识库导入选项，请求中没有的字段保持不变，数组整体替换
+func (ka *KBApi) UpdateOptions(c *gin.Context) {
+	type UpdateOptionsReq struct {
+		KB      string          `json:"kb"`
+		Options json.RawMessage `json:"options"` // 只需包含要修改的字段，其余字段保持不变
+	}
+	type UpdateOptionsRsp struct {
+		BaseRsp
+	}
+
+	req := UpdateOptionsReq{}
+	rsp := UpdateOptionsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 将请求中的字段合并到当前选项
+	opts, err := ReadKBOptions(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if len(req.Options) > 0 {
+		if err := json.Unmarshal(req.Options, opts); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := WriteKBOptions(req.KB, opts); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
 	}
 
 	rsp.Code = 0
@@ -0,0 +1,398 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	indexLogFile       = "indexing-engine.log"
+	defaultLogLimit    = 200
+	maxLogLimit        = 5000
+	logFollowInterval  = 500 * time.Millisecond
+	logFollowHeartbeat = 15 * time.Second
+	maxLogFollowBatch  = 500
+)
+
+// 日志级别，与 Python logging 一致
+var logLevels = []string{"DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"}
+
+// workflowPattern graphrag 开始执行工作流时输出的日志
+var workflowPattern = regexp.MustCompile(`(?i)(?:running|executing|starting) workflow:?\s+([A-Za-z0-9_]+)`)
+
+// LogLine 日志行
+type LogLine struct {
+	Offset   int64  `json:"offset"`         // 行首的字节偏移
+	Line     int    `json:"line,omitempty"` // 行号，从 1 开始；从字节偏移开始读取时为空
+	Level    string `json:"level"`          // 没有级别的行（如 traceback）沿用上一行的级别
+	Workflow string `json:"workflow"`       // 所在的工作流
+	Text     string `json:"text"`
+}
+
+// LogQuery 日志查询，offset、line 与 tail 同时只能使用一个
+type LogQuery struct {
+	Offset   int64    `json:"offset"` // 从字节偏移开始读取
+	Line     int      `json:"line"`   // 从行号开始读取
+	Tail     int      `json:"tail"`   // 读取最后 n 行
+	Limit    int      `json:"limit"`
+	Levels   []string `json:"levels"`
+	Workflow string   `json:"workflow"`
+}
+
+// LogPage 日志查询结果，下一页从 next_offset 开始读取
+type LogPage struct {
+	Size       int64     `json:"size"`
+	NextOffset int64     `json:"next_offset"`
+	EOF        bool      `json:"eof"`
+	Lines      []LogLine `json:"lines"`
+}
+
+// logPath 获取索引版本的日志文件
+func logPath(kb, db string) (string, error) {
+	path, err := dbPath(kb, db)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, "logs", indexLogFile), nil
+}
+
+// levelOf 获取日志行的级别，没有时返回空字符串
+func levelOf(text string) string {
+	for _, field := range strings.Fields(text) {
+		if slices.Contains(logLevels, field) {
+			return field
+		}
+	}
+	return ""
+}
+
+func (q *LogQuery) validate() error {
+	n := 0
+	for _, set := range []bool{q.Offset > 0, q.Line > 0, q.Tail > 0} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one of offset, line and tail can be set")
+	}
+	if q.Offset < 0 || q.Line < 0 || q.Tail < 0 {
+		return fmt.Errorf("offset, line and tail must not be negative")
+	}
+	for i, level := range q.Levels {
+		q.Levels[i] = strings.ToUpper(level)
+		if !slices.Contains(logLevels, q.Levels[i]) {
+			return fmt.Errorf("level '%s' is invalid, must be one of %s", level, strings.Join(logLevels, ", "))
+		}
+	}
+	return nil
+}
+
+func (q *LogQuery) match(l *LogLine) bool {
+	if len(q.Levels) > 0 && !slices.Contains(q.Levels, l.Level) {
+		return false
+	}
+	return q.Workflow == "" || strings.EqualFold(q.Workflow, l.Workflow)
+}
+
+// logScanner 逐行读取日志，记录行号、级别与所在的工作流
+type logScanner struct {
+	r        *bufio.Reader
+	offset   int64
+	line     int // 从文件开头读取时为已读取的行数，否则为 -1
+	level    string
+	workflow string
+	partial  bool   // 是否返回没有换行符的最后一行（可能仍在写入）
+	pending  string // 不返回时已读取的最后一行
+}
+
+func newLogScanner(f *os.File, offset int64, partial bool) (*logScanner, error) {
+	s := logScanner{offset: offset, partial: partial}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		s.line = -1
+	}
+	s.r = bufio.NewReader(f)
+	return &s, nil
+}
+
+// next 读取下一行，没有完整的行时返回 io.EOF
+func (s *logScanner) next() (*LogLine, error) {
+	text, err := s.r.ReadString('\n')
+	text, s.pending = s.pending+text, ""
+	if err != nil {
+		if !errors.Is(err, io.EOF) || text == "" {
+			return nil, err
+		}
+		if !s.partial {
+			s.pending = text
+			return nil, err
+		}
+	}
+
+	l := LogLine{Offset: s.offset, Text: strings.TrimRight(text, "\r\n")}
+	s.offset += int64(len(text))
+	if s.line >= 0 {
+		s.line++
+		l.Line = s.line
+	}
+	if level := levelOf(l.Text); level != "" {
+		s.level = level
+	}
+	if m := workflowPattern.FindStringSubmatch(l.Text); m != nil {
+		s.workflow = m[1]
+	}
+	l.Level, l.Workflow = s.level, s.workflow
+	return &l, nil
+}
+
+// ReadLogPage 读取日志。按工作流过滤时从文件开头读取以确定每行所在的工作流
+func ReadLogPage(path string, q LogQuery) (*LogPage, error) {
+	if err := q.validate(); err != nil {
+		return nil, err
+	}
+	limit := limitOf(q.Limit, defaultLogLimit, maxLogLimit)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("log not found, run indexing first")
+		}
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if q.Offset > stat.Size() {
+		return nil, fmt.Errorf("offset %d is beyond log size %d", q.Offset, stat.Size())
+	}
+
+	start := q.Offset
+	if q.Workflow != "" || q.Tail > 0 {
+		start = 0
+	}
+	s, err := newLogScanner(f, start, true)
+	if err != nil {
+		return nil, err
+	}
+
+	page := LogPage{Size: stat.Size(), Lines: []LogLine{}}
+	for {
+		l, err := s.next()
+		if errors.Is(err, io.EOF) {
+			page.EOF = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.Offset < q.Offset || l.Line > 0 && l.Line < q.Line || !q.match(l) {
+			continue
+		}
+
+		// tail 只保留最后 n 行
+		if q.Tail > 0 {
+			if len(page.Lines) == min(q.Tail, limit) {
+				page.Lines = page.Lines[1:]
+			}
+			page.Lines = append(page.Lines, *l)
+			continue
+		}
+		if len(page.Lines) == limit {
+			page.NextOffset = l.Offset
+			return &page, nil
+		}
+		page.Lines = append(page.Lines, *l)
+	}
+	page.NextOffset = s.offset
+	return &page, nil
+}
+
+// indexRunning 知识库是否正在建立索引
+func indexRunning(kb string) bool {
+	runs, err := ReadIndexRuns(kb)
+	return err == nil && len(runs) > 0 && runs[len(runs)-1].Status == IndexRunRunning
+}
+
+// GetLogs 分页读取索引日志，支持字节偏移、行号、tail 与按级别、工作流过滤
+func (da *DataApi) GetLogs(c *gin.Context) {
+	type GetLogsReq struct {
+		KB string `json:"kb"`
+		DB string `json:"db"`
+		LogQuery
+	}
+	type GetLogsRsp struct {
+		BaseRsp
+		*LogPage
+		Files string `json:"files"` // 本页日志行的文本，兼容旧版本返回整个日志文件的字段
+	}
+
+	req := GetLogsReq{}
+	rsp := GetLogsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	path, err := logPath(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	page, err := ReadLogPage(path, req.LogQuery)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	text := strings.Builder{}
+	for _, l := range page.Lines {
+		text.WriteString(l.Text)
+		text.WriteString("\n")
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.LogPage = page
+	rsp.Files = text.String()
+	c.JSON(http.StatusOK, rsp)
+}
+
+// FollowLogs 通过 SSE 推送当前索引日志的新行，每行为一个 log 事件；
+// 没有正在进行的索引且已读取到文件末尾时发送 end 事件并结束
+func (da *DataApi) FollowLogs(c *gin.Context) {
+	kb := c.Query("kb")
+	q := LogQuery{Levels: c.QueryArray("level"), Workflow: c.Query("workflow")}
+	if err := q.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+	path, err := logPath(kb, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, BaseRsp{Code: -1, Msg: err.Error()})
+		return
+	}
+	// 默认从最近一次索引开始时的位置读取，不推送之前索引的日志
+	start := int64(0)
+	if runs, err := ReadIndexRuns(kb); err == nil && len(runs) > 0 {
+		start = runs[len(runs)-1].LogOffset
+	}
+	offset := start
+	if value := c.Query("offset"); value != "" {
+		if offset, err = strconv.ParseInt(value, 10, 64); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, BaseRsp{Code: -1, Msg: "offset is invalid"})
+			return
+		}
+	}
+	// 从本次索引开始的位置读取以确定每行所在的工作流，不重新解析之前索引的日志；
+	// offset 早于本次索引时直接从 offset 读取
+	start = min(start, offset)
+
+	// 日志文件在索引开始后才创建，打开前先等待
+	var f *os.File
+	var s *logScanner
+	open := func() error {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		// 日志被清空或轮转后从头读取
+		if stat, err := f.Stat(); err == nil && start > stat.Size() {
+			start, offset = 0, 0
+		}
+		if s, err = newLogScanner(f, start, false); err != nil {
+			f.Close()
+			f = nil
+		}
+		return err
+	}
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(logFollowInterval)
+	defer ticker.Stop()
+	heartbeat := time.Now()
+	c.Stream(func(w io.Writer) bool {
+		running := indexRunning(kb)
+		if f == nil {
+			if err := open(); err != nil {
+				if !os.IsNotExist(err) {
+					c.SSEvent("error", err.Error())
+					return false
+				}
+				if !running {
+					c.SSEvent("end", offset)
+					return false
+				}
+			}
+		}
+
+		// 索引结束后读取没有换行符的最后一行
+		sent := 0
+		for s != nil && sent < maxLogFollowBatch {
+			s.partial = !running
+			l, err := s.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				c.SSEvent("error", err.Error())
+				return false
+			}
+			if l.Offset < offset || !q.match(l) {
+				continue
+			}
+			c.SSEvent("log", l)
+			sent++
+		}
+		if sent > 0 {
+			heartbeat = time.Now()
+			return true
+		}
+
+		if !running && s != nil {
+			c.SSEvent("end", s.offset)
+			return false
+		}
+		if time.Since(heartbeat) > logFollowHeartbeat {
+			c.SSEvent("ping", "")
+			heartbeat = time.Now()
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	})
+}
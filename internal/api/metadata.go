@@ -0,0 +1,347 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	metadataFile = "metadata.json"
+
+	// 建立索引时生成的 CSV 输入与对应的配置文件，位于知识库目录下
+	metadataInputDir      = "metadata_input"
+	metadataInputFile     = "documents.csv"
+	metadataSettingsFile  = "settings.metadata.yaml"
+	defaultInputPattern   = `.*\.txt$`
+	metadataDateLayout    = "2006-01-02"
+	metadataTagsSeparator = ","
+)
+
+// metadataColumns CSV 中的固定列，自定义属性不能与之重名
+var metadataColumns = []string{"text", "title", "file", "source", "date", "tags"}
+
+var attributeKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DocMetadata 输入文件的元数据，建立索引时作为文档属性传给 graphrag
+type DocMetadata struct {
+	Title      string            `json:"title,omitempty"`      // 标题，为空时使用文件名
+	Source     string            `json:"source,omitempty"`     // 来源，如发文单位
+	Date       string            `json:"date,omitempty"`       // 日期，如生效日期，格式为 2006-01-02
+	Tags       []string          `json:"tags,omitempty"`       // 标签
+	Attributes map[string]string `json:"attributes,omitempty"` // 自定义属性
+}
+
+// Validate 校验元数据
+func (m *DocMetadata) Validate() error {
+	if m.Date != "" {
+		if _, err := time.Parse(metadataDateLayout, m.Date); err != nil {
+			return fmt.Errorf("date '%s' is not in format YYYY-MM-DD", m.Date)
+		}
+	}
+	for _, tag := range m.Tags {
+		if strings.TrimSpace(tag) == "" || strings.Contains(tag, metadataTagsSeparator) {
+			return fmt.Errorf("tag '%s' is empty or contains '%s'", tag, metadataTagsSeparator)
+		}
+	}
+	for key := range m.Attributes {
+		if !attributeKeyRegexp.MatchString(key) {
+			return fmt.Errorf("attribute key '%s' is invalid, only letters, digits and _ are allowed", key)
+		}
+		if slices.Contains(metadataColumns, key) {
+			return fmt.Errorf("attribute key '%s' is reserved", key)
+		}
+	}
+	return nil
+}
+
+var metadataLock sync.Mutex
+
+// ReadMetadata 获取知识库所有输入文件的元数据，key 为文件名
+func ReadMetadata(kb string) (map[string]DocMetadata, error) {
+	all := map[string]DocMetadata{}
+	data, err := os.ReadFile(kbPath(kb, metadataFile))
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// WriteDocMetadata 保存输入文件的元数据，meta 为 nil 时删除
+func WriteDocMetadata(kb, file string, meta *DocMetadata) error {
+	if meta != nil {
+		if err := meta.Validate(); err != nil {
+			return err
+		}
+	}
+
+	metadataLock.Lock()
+	defer metadataLock.Unlock()
+
+	all, err := ReadMetadata(kb)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		delete(all, file)
+	} else {
+		all[file] = *meta
+	}
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(kbPath(kb, metadataFile), data)
+}
+
+// metadataInputs 获取 graphrag 文本模式下会读取的输入文件，key 为读取的文件，value 为元数据对应的文件
+// 转换得到的 txt 使用原文件的元数据
+func metadataInputs(kb, pattern string) (map[string]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("input.file_pattern: %w", err)
+	}
+	files, err := ReadInput(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := map[string]string{}
+	for _, file := range files {
+		if !re.MatchString(file) {
+			continue
+		}
+		inputs[file] = file
+		if isConverted(kb, file) {
+			inputs[file] = strings.TrimSuffix(file, ".txt")
+		}
+	}
+	return inputs, nil
+}
+
+// PrepareMetadataInput 知识库有元数据时，将文本输入与元数据合并生成 CSV，并生成使用该 CSV 的配置文件
+// 返回配置文件路径；CSV 模式或没有元数据时返回空字符串，直接使用 settings.yaml
+func PrepareMetadataInput(kb string) (string, error) {
+	// 先删除上次生成的文件，避免元数据删除后仍使用旧的配置
+	os.RemoveAll(kbPath(kb, metadataInputDir))
+	os.Remove(kbPath(kb, metadataSettingsFile))
+
+	cfg, err := ReadSettings(kb)
+	if err != nil {
+		return "", err
+	}
+	if csvMappingOf(cfg) != nil {
+		return "", nil
+	}
+	all, err := ReadMetadata(kb)
+	if err != nil || len(all) == 0 {
+		return "", err
+	}
+
+	pattern := defaultInputPattern
+	if cfg.Input != nil && cfg.Input.FilePattern != "" {
+		pattern = cfg.Input.FilePattern
+	}
+	inputs, err := metadataInputs(kb, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// 自定义属性的并集作为额外的列
+	keys := []string{}
+	files := make([]string, 0, len(inputs))
+	for file, source := range inputs {
+		files = append(files, file)
+		for key := range all[source].Attributes {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(files)
+	columns := append(append([]string{}, metadataColumns...), keys...)
+
+	if err := os.MkdirAll(kbPath(kb, metadataInputDir), os.ModePerm); err != nil {
+		return "", err
+	}
+	f, err := os.Create(kbPath(kb, metadataInputDir, metadataInputFile))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(columns); err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		text, err := os.ReadFile(kbPath(kb, "input", file))
+		if err != nil {
+			return "", err
+		}
+		meta := all[inputs[file]]
+		title := meta.Title
+		if title == "" {
+			title = inputs[file]
+		}
+		row := []string{string(text), title, inputs[file], meta.Source, meta.Date,
+			strings.Join(meta.Tags, metadataTagsSeparator)}
+		for _, key := range keys {
+			row = append(row, meta.Attributes[key])
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+
+	// 在 settings.yaml 的基础上修改 input 配置
+	doc, err := loadSettingsNode(kb)
+	if err != nil {
+		return "", err
+	}
+	src := &yaml.Node{}
+	if err := src.Encode(map[string]any{
+		"input": map[string]any{
+			"file_type":                  "csv",
+			"base_dir":                   metadataInputDir,
+			"file_pattern":               csvFilePattern,
+			"text_column":                "text",
+			"title_column":               "title",
+			"document_attribute_columns": columns[2:],
+		},
+	}); err != nil {
+		return "", err
+	}
+	mergeNode(doc.Content[0], src, false)
+	data, err := encodeSettingsNode(doc)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(kbPath(kb, metadataSettingsFile), data); err != nil {
+		return "", err
+	}
+	return kbPath(kb, metadataSettingsFile), nil
+}
+
+// GetMetadata 获取输入文件的元数据，file 为空时返回所有文件
+func (ka *KBApi) GetMetadata(c *gin.Context) {
+	type GetMetadataReq struct {
+		KB   string `json:"kb"`
+		File string `json:"file"`
+	}
+	type GetMetadataRsp struct {
+		BaseRsp
+		Metadata map[string]DocMetadata `json:"metadata"`
+	}
+
+	req := GetMetadataReq{}
+	rsp := GetMetadataRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	all, err := ReadMetadata(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if req.File != "" {
+		filtered := map[string]DocMetadata{}
+		if meta, ok := all[req.File]; ok {
+			filtered[req.File] = meta
+		}
+		all = filtered
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Metadata = all
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UpdateMetadata 修改输入文件的元数据，metadata 为 null 时删除
+func (ka *KBApi) UpdateMetadata(c *gin.Context) {
+	type UpdateMetadataReq struct {
+		KB       string       `json:"kb"`
+		File     string       `json:"file"`
+		Metadata *DocMetadata `json:"metadata"`
+	}
+	type UpdateMetadataRsp struct {
+		BaseRsp
+	}
+
+	req := UpdateMetadataReq{}
+	rsp := UpdateMetadataRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 删除时不要求文件存在，便于清理已删除文件的元数据
+	if req.Metadata != nil {
+		if _, err := inputFile(req.KB, req.File); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusNotFound, rsp)
+			return
+		}
+	} else if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	} else if err := checkName(req.File); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := WriteDocMetadata(req.KB, req.File, req.Metadata); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	optionsFile = "options.yaml"
+)
+
+// KBOptions 知识库的导入选项，保存在知识库目录下的 options.yaml
+type KBOptions struct {
+	Upload UploadOptions `yaml:"upload" json:"upload"`
+	Clean  CleanOptions  `yaml:"clean" json:"clean"`
+}
+
+// UploadOptions 上传选项
+type UploadOptions struct {
+	Extensions  []string `yaml:"extensions" json:"extensions"`       // 允许上传的文件扩展名
+	MaxFileSize int64    `yaml:"max_file_size" json:"max_file_size"` // 单个文件大小上限（字节），0 表示不限制
+	MaxKBSize   int64    `yaml:"max_kb_size" json:"max_kb_size"`     // 知识库输入文件总大小上限（字节），0 表示不限制
+	Duplicate   string   `yaml:"duplicate" json:"duplicate"`         // 内容重复时的处理策略：reject、link、warn
+}
+
+// DefaultKBOptions 默认导入选项
+func DefaultKBOptions() KBOptions {
+	return KBOptions{
+		Upload: UploadOptions{
+			Extensions:  append([]string{".txt", ".csv"}, convertExtensions...),
+			MaxFileSize: 512 << 20,
+			MaxKBSize:   1 << 30,
+			Duplicate:   DuplicateWarn,
+		},
+		Clean: CleanOptions{
+			Mode:  CleanOff,
+			Steps: DefaultCleanSteps(),
+		},
+	}
+}
+
+// Validate 校验导入选项
+func (o *KBOptions) Validate() error {
+	for _, ext := range o.Upload.Extensions {
+		if !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("upload.extensions: '%s' is invalid", ext)
+		}
+	}
+	if o.Upload.MaxFileSize < 0 {
+		return fmt.Errorf("upload.max_file_size must not be negative")
+	}
+	if o.Upload.MaxKBSize < 0 {
+		return fmt.Errorf("upload.max_kb_size must not be negative")
+	}
+	if o.Upload.Duplicate != "" {
+		if err := checkDuplicatePolicy(o.Upload.Duplicate); err != nil {
+			return err
+		}
+	}
+	return o.Clean.Validate()
+}
+
+// AllowExtension 判断是否允许上传该文件
+func (o *KBOptions) AllowExtension(filename string) bool {
+	if len(o.Upload.Extensions) == 0 {
+		return true
+	}
+	return slices.Contains(o.Upload.Extensions, strings.ToLower(filepath.Ext(filename)))
+}
+
+// readOptionsFile 读取导入选项文件，文件不存在时返回默认选项
+func readOptionsFile(path string) (*KBOptions, error) {
+	opts := DefaultKBOptions()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &opts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &opts); err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+func writeOptionsFile(path string, opts *KBOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// ReadKBOptions 获取知识库导入选项
+func ReadKBOptions(kb string) (*KBOptions, error) {
+	return readOptionsFile(kbPath(kb, optionsFile))
+}
+
+// WriteKBOptions 保存知识库导入选项
+func WriteKBOptions(kb string, opts *KBOptions) error {
+	return writeOptionsFile(kbPath(kb, optionsFile), opts)
+}
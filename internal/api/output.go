@@ -0,0 +1,599 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+)
+
+// 索引输出表
+const (
+	TableEntities         = "entities"
+	TableNodes            = "nodes"
+	TableRelationships    = "relationships"
+	TableCommunities      = "communities"
+	TableCommunityReports = "community_reports"
+	TableTextUnits        = "text_units"
+	TableDocuments        = "documents"
+)
+
+const (
+	defaultDB            = "output"
+	defaultTablePageSize = 20
+	maxTablePageSize     = 1000
+)
+
+// 过滤操作
+const (
+	FilterEq       = "eq"
+	FilterNe       = "ne"
+	FilterGt       = "gt"
+	FilterGte      = "gte"
+	FilterLt       = "lt"
+	FilterLte      = "lte"
+	FilterContains = "contains" // 字符串包含（不区分大小写）或列表包含元素
+	FilterIn       = "in"       // 取值在列表中
+)
+
+var filterOps = []string{FilterEq, FilterNe, FilterGt, FilterGte, FilterLt, FilterLte, FilterContains, FilterIn}
+
+// OutputTable 索引输出表，列表类型的列为 []any
+type OutputTable struct {
+	Columns []string
+	Rows    []map[string]any
+}
+
+// TableFilter 过滤条件
+type TableFilter struct {
+	Field string `json:"field"`
+	Op    string `json:"op"` // 为空时为 eq
+	Value any    `json:"value"`
+}
+
+// TableQuery 表查询：过滤、排序、分页与字段投影
+type TableQuery struct {
+	Filters  []TableFilter `json:"filters"`
+	Sort     string        `json:"sort"`
+	Desc     bool          `json:"desc"`
+	Fields   []string      `json:"fields"` // 为空时返回除向量外的所有列
+	Page     int           `json:"page"`   // 从 1 开始
+	PageSize int           `json:"page_size"`
+}
+
+// TablePage 表查询结果
+type TablePage struct {
+	Columns  []string         `json:"columns"`
+	Total    int              `json:"total"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+	Rows     []map[string]any `json:"rows"`
+}
+
+// maxCachedTableRows 缓存的输出表总行数上限，超过时删除最久未使用的表
+const maxCachedTableRows = 2000000
+
+// fileStamp 文件大小与修改时间，用于判断缓存是否有效
+type fileStamp struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func stampOf(path string) (fileStamp, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// outputTableCache 读取过的输出表，parquet 文件（以及补充 degree 的节点表）大小与修改时间不变时复用
+type outputTableCache struct {
+	Stamp fileStamp
+	Nodes *fileStamp // 从节点表补充了 degree 时为节点表的状态
+	Table *OutputTable
+	used  uint64 // 最近一次使用的序号
+}
+
+// outputTableLoad 正在读取的输出表，同一文件只读取一次，其它请求等待读取完成
+type outputTableLoad struct {
+	done chan struct{}
+	err  error
+}
+
+var (
+	outputTables     = map[string]outputTableCache{}
+	outputTableLoads = map[string]*outputTableLoad{}
+	outputTableTick  uint64
+	outputTableMux   sync.Mutex
+)
+
+// errTableNotFound 索引版本中没有该输出表，可用 errors.Is 与读取失败区分
+var errTableNotFound = errors.New("run indexing first")
+
+// outputPath 获取索引输出目录，db 为空时为当前的 output
+func outputPath(kb, db string) (string, error) {
+	path, err := dbPath(kb, db)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(path, "output"), nil
+}
+
+// tableFile 获取输出表对应的 parquet 文件，兼容 graphrag 1.0 之前的 create_final_ 前缀
+func tableFile(kb, db, table string) (string, error) {
+	path, err := outputPath(kb, db)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{"create_final_" + table + ".parquet", table + ".parquet"} {
+		file := filepath.Join(path, name)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	if db == "" {
+		db = defaultDB
+	}
+	return "", fmt.Errorf("table '%s' not found in db '%s', %w", table, db, errTableNotFound)
+}
+
+// readParquet 读取 parquet 文件的所有行，跳过 pandas 写入的索引列
+func readParquet(path string) (*OutputTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pf, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", stat.Name(), err)
+	}
+
+	table := OutputTable{Rows: make([]map[string]any, 0, pf.NumRows())}
+	for _, field := range pf.Schema().Fields() {
+		if !strings.HasPrefix(field.Name(), "__") {
+			table.Columns = append(table.Columns, field.Name())
+		}
+	}
+
+	r := parquet.NewReader(pf)
+	defer r.Close()
+	for {
+		row := map[string]any{}
+		if err := r.Read(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", stat.Name(), err)
+		}
+		for key, value := range row {
+			if strings.HasPrefix(key, "__") {
+				delete(row, key)
+				continue
+			}
+			row[key] = normalizeValue(value)
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return &table, nil
+}
+
+// normalizeValue 将 parquet 读出的值转为可以编码为 JSON 的值，NaN 与 Inf（pandas 的缺失值）转为 nil
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case float32:
+		return normalizeValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = normalizeValue(v[i])
+		}
+		return v
+	case map[string]any:
+		for key := range v {
+			v[key] = normalizeValue(v[key])
+		}
+		return v
+	}
+	return value
+}
+
+// ReadOutputTable 读取索引输出表，实体表缺少 degree 时从节点表补充
+// 读取 parquet 时不持有 outputTableMux，只有读取同一文件的请求需要等待
+func ReadOutputTable(kb, db, table string) (*OutputTable, error) {
+	path, err := tableFile(kb, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		stamp, err := stampOf(path)
+		if err != nil {
+			return nil, err
+		}
+
+		outputTableMux.Lock()
+		if cache, ok := outputTables[path]; ok && cache.Stamp == stamp && cache.Nodes.valid(kb, db) {
+			outputTableTick++
+			cache.used = outputTableTick
+			outputTables[path] = cache
+			outputTableMux.Unlock()
+			return cache.Table, nil
+		}
+		if load, ok := outputTableLoads[path]; ok {
+			outputTableMux.Unlock()
+			<-load.done
+			if load.err != nil {
+				return nil, load.err
+			}
+			// 读取期间文件可能再次改变，重新检查缓存
+			continue
+		}
+		load := &outputTableLoad{done: make(chan struct{})}
+		outputTableLoads[path] = load
+		outputTableMux.Unlock()
+
+		cache, err := loadOutputTable(kb, db, table, path, stamp)
+
+		outputTableMux.Lock()
+		delete(outputTableLoads, path)
+		if err == nil {
+			outputTableTick++
+			cache.used = outputTableTick
+			outputTables[path] = *cache
+			trimOutputTables(path)
+		}
+		load.err = err
+		close(load.done)
+		outputTableMux.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		return cache.Table, nil
+	}
+}
+
+// valid 判断补充 degree 的节点表是否没有改变，没有从节点表补充时总是有效
+func (nodes *fileStamp) valid(kb, db string) bool {
+	if nodes == nil {
+		return true
+	}
+	path, err := tableFile(kb, db, TableNodes)
+	if err != nil {
+		return false
+	}
+	stamp, err := stampOf(path)
+	return err == nil && stamp == *nodes
+}
+
+// loadOutputTable 读取 parquet 文件，实体表缺少 degree 时从节点表补充
+func loadOutputTable(kb, db, table, path string, stamp fileStamp) (*outputTableCache, error) {
+	t, err := readParquet(path)
+	if err != nil {
+		return nil, err
+	}
+	cache := outputTableCache{Stamp: stamp, Table: t}
+	if table == TableEntities && !slices.Contains(t.Columns, "degree") {
+		if nodes, err := tableFile(kb, db, TableNodes); err == nil {
+			if nodesStamp, err := stampOf(nodes); err == nil {
+				if n, err := readParquet(nodes); err == nil {
+					joinDegree(t, n)
+					cache.Nodes = &nodesStamp
+				}
+			}
+		}
+	}
+	return &cache, nil
+}
+
+// trimOutputTables 缓存的总行数超过 maxCachedTableRows 时删除最久未使用的表，保留 keep，需持有 outputTableMux
+func trimOutputTables(keep string) {
+	rows := 0
+	for _, cache := range outputTables {
+		rows += len(cache.Table.Rows)
+	}
+	for rows > maxCachedTableRows {
+		oldest := ""
+		for path, cache := range outputTables {
+			if path != keep && (oldest == "" || cache.used < outputTables[oldest].used) {
+				oldest = path
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		rows -= len(outputTables[oldest].Table.Rows)
+		delete(outputTables, oldest)
+	}
+}
+
+// evictOutputTables 删除匹配的索引版本的输出表
+func evictOutputTables(m cacheMatcher) {
+	outputTableMux.Lock()
+	defer outputTableMux.Unlock()
+	for path := range outputTables {
+		if m.matchPath(path) {
+			delete(outputTables, path)
+		}
+	}
+}
+
+// joinDegree 按 title 将节点表的 degree 补充到实体表，节点表每个层级一行，degree 相同
+func joinDegree(entities, nodes *OutputTable) {
+	degrees := map[any]any{}
+	for _, node := range nodes.Rows {
+		degrees[node["title"]] = node["degree"]
+	}
+	entities.Columns = append(entities.Columns, "degree")
+	for _, row := range entities.Rows {
+		row["degree"] = degrees[row["title"]]
+	}
+}
+
+// toFloat 将数值转为 float64
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// compareValues 比较两个值，数值按大小，其他按字符串比较
+func compareValues(a, b any) int {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func (f *TableFilter) validate(columns []string) error {
+	if f.Op == "" {
+		f.Op = FilterEq
+	}
+	if !slices.Contains(filterOps, f.Op) {
+		return fmt.Errorf("filter op '%s' is invalid, must be one of %s", f.Op, strings.Join(filterOps, ", "))
+	}
+	if !slices.Contains(columns, f.Field) {
+		return fmt.Errorf("filter field '%s' not exists", f.Field)
+	}
+	if _, ok := f.Value.([]any); f.Op == FilterIn && !ok {
+		return fmt.Errorf("filter value of op 'in' must be a list")
+	}
+	return nil
+}
+
+func (f *TableFilter) match(row map[string]any) bool {
+	value := row[f.Field]
+	switch f.Op {
+	case FilterEq:
+		return value != nil && compareValues(value, f.Value) == 0
+	case FilterNe:
+		return value == nil || compareValues(value, f.Value) != 0
+	case FilterGt:
+		return value != nil && compareValues(value, f.Value) > 0
+	case FilterGte:
+		return value != nil && compareValues(value, f.Value) >= 0
+	case FilterLt:
+		return value != nil && compareValues(value, f.Value) < 0
+	case FilterLte:
+		return value != nil && compareValues(value, f.Value) <= 0
+	case FilterContains:
+		if list, ok := value.([]any); ok {
+			return slices.ContainsFunc(list, func(v any) bool { return compareValues(v, f.Value) == 0 })
+		}
+		s, ok := value.(string)
+		return ok && strings.Contains(strings.ToLower(s), strings.ToLower(fmt.Sprint(f.Value)))
+	case FilterIn:
+		return value != nil && slices.ContainsFunc(f.Value.([]any), func(v any) bool { return compareValues(value, v) == 0 })
+	}
+	return false
+}
+
+// isVectorColumn 向量列默认不返回
+func isVectorColumn(name string) bool {
+	return strings.HasSuffix(name, "_embedding")
+}
+
+// pageRange 获取第 page 页（从 1 开始）的下标范围，page 超出范围时为空，不会溢出
+func pageRange(total, page, pageSize int) (start, end int) {
+	if page < 1 || pageSize < 1 || page-1 >= (total+pageSize-1)/pageSize {
+		return total, total
+	}
+	start = (page - 1) * pageSize
+	return start, min(start+pageSize, total)
+}
+
+// QueryTable 过滤、排序并分页
+func QueryTable(t *OutputTable, q TableQuery) (*TablePage, error) {
+	for i := range q.Filters {
+		if err := q.Filters[i].validate(t.Columns); err != nil {
+			return nil, err
+		}
+	}
+	if q.Sort != "" && !slices.Contains(t.Columns, q.Sort) {
+		return nil, fmt.Errorf("sort field '%s' not exists", q.Sort)
+	}
+	fields := q.Fields
+	for _, field := range fields {
+		if !slices.Contains(t.Columns, field) {
+			return nil, fmt.Errorf("field '%s' not exists", field)
+		}
+	}
+	if len(fields) == 0 {
+		for _, col := range t.Columns {
+			if !isVectorColumn(col) {
+				fields = append(fields, col)
+			}
+		}
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = defaultTablePageSize
+	}
+	q.PageSize = min(q.PageSize, maxTablePageSize)
+
+	rows := []map[string]any{}
+	for _, row := range t.Rows {
+		if !slices.ContainsFunc(q.Filters, func(f TableFilter) bool { return !f.match(row) }) {
+			rows = append(rows, row)
+		}
+	}
+
+	// 空值排在最后
+	if q.Sort != "" {
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i][q.Sort], rows[j][q.Sort]
+			if a == nil || b == nil {
+				return a != nil
+			}
+			if q.Desc {
+				return compareValues(a, b) > 0
+			}
+			return compareValues(a, b) < 0
+		})
+	}
+
+	page := TablePage{
+		Columns:  fields,
+		Total:    len(rows),
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		Rows:     []map[string]any{},
+	}
+	start, end := pageRange(len(rows), q.Page, q.PageSize)
+	for _, row := range rows[start:end] {
+		projected := make(map[string]any, len(fields))
+		for _, field := range fields {
+			projected[field] = row[field]
+		}
+		page.Rows = append(page.Rows, projected)
+	}
+	return &page, nil
+}
+
+// queryTable 查询索引输出表的通用处理
+func (da *DataApi) queryTable(c *gin.Context, table string) {
+	type QueryTableReq struct {
+		KB string `json:"kb"`
+		DB string `json:"db"`
+		TableQuery
+	}
+	type QueryTableRsp struct {
+		BaseRsp
+		*TablePage
+	}
+
+	req := QueryTableReq{}
+	rsp := QueryTableRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	t, err := ReadOutputTable(req.KB, req.DB, table)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	page, err := QueryTable(t, req.TableQuery)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.TablePage = page
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetEntities 查询实体表
+func (da *DataApi) GetEntities(c *gin.Context) {
+	da.queryTable(c, TableEntities)
+}
+
+// GetRelationships 查询关系表
+func (da *DataApi) GetRelationships(c *gin.Context) {
+	da.queryTable(c, TableRelationships)
+}
+
+// GetCommunities 查询社区表
+func (da *DataApi) GetCommunities(c *gin.Context) {
+	da.queryTable(c, TableCommunities)
+}
+
+// GetCommunityReports 查询社区报告表
+func (da *DataApi) GetCommunityReports(c *gin.Context) {
+	da.queryTable(c, TableCommunityReports)
+}
+
+// GetTextUnits 查询文本块表
+func (da *DataApi) GetTextUnits(c *gin.Context) {
+	da.queryTable(c, TableTextUnits)
+}
+
+// GetDocuments 查询文档表
+func (da *DataApi) GetDocuments(c *gin.Context) {
+	da.queryTable(c, TableDocuments)
+}
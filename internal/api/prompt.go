@@ -0,0 +1,625 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	promptDir        = "prompts"
+	promptVersionDir = "prompt_versions"
+	promptIndexFile  = "versions.json"
+)
+
+// 提示词版本来源
+const (
+	PromptSourceInitial = "initial"
+	PromptSourceUpdate  = "update"
+	PromptSourceTune    = "tune"
+)
+
+// promptPlaceholders GraphRAG 各提示词必须包含的占位变量
+var promptPlaceholders = map[string][]string{
+	"entity_extraction.txt":                  {"entity_types", "input_text", "tuple_delimiter", "record_delimiter", "completion_delimiter"},
+	"summarize_descriptions.txt":             {"entity_name", "description_list"},
+	"claim_extraction.txt":                   {"entity_specs", "claim_description", "input_text", "tuple_delimiter", "record_delimiter", "completion_delimiter"},
+	"community_report.txt":                   {"input_text"},
+	"local_search_system_prompt.txt":         {"context_data", "response_type"},
+	"global_search_map_system_prompt.txt":    {"context_data"},
+	"global_search_reduce_system_prompt.txt": {"report_data", "response_type"},
+	"drift_search_system_prompt.txt":         {"context_data", "response_type", "global_query"},
+	"question_gen_system_prompt.txt":         {"context_data", "question_count"},
+}
+
+// 匹配 {name} 形式的占位变量，排除 {{ }} 转义
+var placeholderRegexp = regexp.MustCompile(`(?:^|[^{])\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var promptLock sync.Mutex
+
+type PromptApi struct {
+}
+
+func (pa *PromptApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/prompt")
+
+	r.POST("", pa.GetPrompts)
+	r.POST("/get", pa.GetPrompt)
+	r.POST("/update", pa.UpdatePrompt)
+	r.POST("/versions", pa.GetPromptVersions)
+	r.POST("/diff", pa.DiffPrompt)
+	r.POST("/rollback", pa.RollbackPrompt)
+	r.POST("/activate", pa.RollbackPrompt)
+	r.POST("/tune", pa.TunePrompt)
+}
+
+// PromptInfo 提示词信息
+type PromptInfo struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	ModTime       time.Time `json:"mod_time"`
+	ActiveVersion string    `json:"active_version"`
+	Versions      int       `json:"versions"`
+}
+
+// PromptVersion 提示词版本
+type PromptVersion struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Source    string    `json:"source"`
+	Comment   string    `json:"comment"`
+	Size      int       `json:"size"`
+}
+
+// promptHistory 单个提示词的版本记录
+type promptHistory struct {
+	Active   string          `json:"active"`
+	Versions []PromptVersion `json:"versions"`
+}
+
+func checkPromptName(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if filepath.Ext(name) != ".txt" {
+		return fmt.Errorf("prompt '%s' must be a .txt file", name)
+	}
+	return nil
+}
+
+// checkPromptPlaceholders 检查提示词占位变量，缺少必需变量返回错误，未知变量返回警告
+func checkPromptPlaceholders(name, content string) (errs []string, warnings []string) {
+	found := map[string]bool{}
+	for _, m := range placeholderRegexp.FindAllStringSubmatch(content, -1) {
+		found[m[1]] = true
+	}
+
+	required, known := promptPlaceholders[name]
+	for _, p := range required {
+		if !found[p] {
+			errs = append(errs, fmt.Sprintf("missing placeholder {%s}", p))
+		}
+	}
+	if known {
+		for p := range found {
+			if !slices.Contains(required, p) {
+				warnings = append(warnings, fmt.Sprintf("unknown placeholder {%s}, escape it as {{%s}} if it is literal text", p, p))
+			}
+		}
+		slices.Sort(warnings)
+	}
+	return errs, warnings
+}
+
+func readPromptHistory(kb, name string) (*promptHistory, error) {
+	history := promptHistory{}
+	data, err := os.ReadFile(kbPath(kb, promptVersionDir, name, promptIndexFile))
+	if os.IsNotExist(err) {
+		return &history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+func writePromptHistory(kb, name string, history *promptHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(kbPath(kb, promptVersionDir, name, promptIndexFile), data)
+}
+
+// ReadPrompts 获取知识库所有提示词
+func ReadPrompts(kb string) ([]PromptInfo, error) {
+	files, err := os.ReadDir(kbPath(kb, promptDir))
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := []PromptInfo{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".txt" {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, err
+		}
+		history, err := readPromptHistory(kb, file.Name())
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, PromptInfo{
+			Name:          file.Name(),
+			Size:          info.Size(),
+			ModTime:       info.ModTime(),
+			ActiveVersion: history.Active,
+			Versions:      len(history.Versions),
+		})
+	}
+
+	return prompts, nil
+}
+
+// ReadPrompt 获取提示词内容，version 为空时返回当前生效的内容
+func ReadPrompt(kb, name, version string) (string, error) {
+	path := kbPath(kb, promptDir, name)
+	if version != "" {
+		if err := checkName(version); err != nil {
+			return "", err
+		}
+		path = kbPath(kb, promptVersionDir, name, version+".txt")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("prompt '%s' version '%s' not exists", name, version)
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// newPromptVersion 保存一个新的提示词版本，需持有 promptLock
+func newPromptVersion(kb, name string, history *promptHistory, content, source, comment string) (*PromptVersion, error) {
+	dir := kbPath(kb, promptVersionDir, name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	version := now.Format("20060102-150405")
+	for i := 2; slices.ContainsFunc(history.Versions, func(v PromptVersion) bool { return v.Version == version }); i++ {
+		version = fmt.Sprintf("%s-%d", now.Format("20060102-150405"), i)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, version+".txt"), []byte(content)); err != nil {
+		return nil, err
+	}
+
+	v := PromptVersion{
+		Version:   version,
+		CreatedAt: now,
+		Source:    source,
+		Comment:   comment,
+		Size:      len(content),
+	}
+	history.Versions = append(history.Versions, v)
+	return &v, nil
+}
+
+// SavePromptVersion 保存提示词的新版本，activate 为 true 时同时写入 prompts 目录生效
+// 首次保存时会将当前生效的内容记录为初始版本，以便回滚
+func SavePromptVersion(kb, name, content, source, comment string, activate bool) (*PromptVersion, error) {
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	history, err := readPromptHistory(kb, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.Versions) == 0 {
+		current, err := os.ReadFile(kbPath(kb, promptDir, name))
+		if err == nil {
+			v, err := newPromptVersion(kb, name, history, string(current), PromptSourceInitial, "")
+			if err != nil {
+				return nil, err
+			}
+			history.Active = v.Version
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	v, err := newPromptVersion(kb, name, history, content, source, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	if activate {
+		if err := os.MkdirAll(kbPath(kb, promptDir), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := writeFileAtomic(kbPath(kb, promptDir, name), []byte(content)); err != nil {
+			return nil, err
+		}
+		history.Active = v.Version
+	}
+
+	if err := writePromptHistory(kb, name, history); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ActivatePromptVersion 将指定版本写入 prompts 目录使其生效
+// 版本缺少必需的占位变量时不生效，通过 errs 返回缺少的变量
+func ActivatePromptVersion(kb, name, version string) (errs []string, err error) {
+	promptLock.Lock()
+	defer promptLock.Unlock()
+
+	history, err := readPromptHistory(kb, name)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(history.Versions, func(v PromptVersion) bool { return v.Version == version }) {
+		return nil, fmt.Errorf("prompt '%s' version '%s' not exists", name, version)
+	}
+
+	content, err := ReadPrompt(kb, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if errs, _ := checkPromptPlaceholders(name, content); len(errs) > 0 {
+		return errs, nil
+	}
+	if err := os.MkdirAll(kbPath(kb, promptDir), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(kbPath(kb, promptDir, name), []byte(content)); err != nil {
+		return nil, err
+	}
+
+	history.Active = version
+	return nil, writePromptHistory(kb, name, history)
+}
+
+// GetPrompts 获取知识库提示词列表
+func (pa *PromptApi) GetPrompts(c *gin.Context) {
+	type GetPromptsReq struct {
+		KB string `json:"kb"`
+	}
+	type GetPromptsRsp struct {
+		BaseRsp
+		Prompts []PromptInfo `json:"prompts"`
+	}
+
+	req := GetPromptsReq{}
+	rsp := GetPromptsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	prompts, err := ReadPrompts(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Prompts = prompts
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetPrompt 获取提示词内容
+func (pa *PromptApi) GetPrompt(c *gin.Context) {
+	type GetPromptReq struct {
+		KB      string `json:"kb"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	type GetPromptRsp struct {
+		BaseRsp
+		Content string `json:"content"`
+	}
+
+	req := GetPromptReq{}
+	rsp := GetPromptRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := checkPromptName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	content, err := ReadPrompt(req.KB, req.Name, req.Version)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Content = content
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UpdatePrompt 修改提示词，保存为新版本并生效
+func (pa *PromptApi) UpdatePrompt(c *gin.Context) {
+	type UpdatePromptReq struct {
+		KB      string `json:"kb"`
+		Name    string `json:"name"`
+		Content string `json:"content"`
+		Comment string `json:"comment"`
+	}
+	type UpdatePromptRsp struct {
+		BaseRsp
+		Version  *PromptVersion `json:"version,omitempty"`
+		Errors   []string       `json:"errors,omitempty"`
+		Warnings []string       `json:"warnings,omitempty"`
+	}
+
+	req := UpdatePromptReq{}
+	rsp := UpdatePromptRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := checkPromptName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	errs, warnings := checkPromptPlaceholders(req.Name, req.Content)
+	if len(errs) > 0 {
+		rsp.Code = -1
+		rsp.Msg = "invalid prompt: " + strings.Join(errs, "; ")
+		rsp.Errors = errs
+		rsp.Warnings = warnings
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	v, err := SavePromptVersion(req.KB, req.Name, req.Content, PromptSourceUpdate, req.Comment, true)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Version = v
+	rsp.Warnings = warnings
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetPromptVersions 获取提示词版本列表
+func (pa *PromptApi) GetPromptVersions(c *gin.Context) {
+	type GetPromptVersionsReq struct {
+		KB   string `json:"kb"`
+		Name string `json:"name"`
+	}
+	type GetPromptVersionsRsp struct {
+		BaseRsp
+		Active   string          `json:"active"`
+		Versions []PromptVersion `json:"versions"`
+	}
+
+	req := GetPromptVersionsReq{}
+	rsp := GetPromptVersionsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := checkPromptName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	history, err := readPromptHistory(req.KB, req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Active = history.Active
+	rsp.Versions = history.Versions
+	c.JSON(http.StatusOK, rsp)
+}
+
+// DiffPrompt 比较提示词两个版本，版本为空时表示当前生效的内容
+func (pa *PromptApi) DiffPrompt(c *gin.Context) {
+	type DiffPromptReq struct {
+		KB   string `json:"kb"`
+		Name string `json:"name"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	type DiffPromptRsp struct {
+		BaseRsp
+		Diff string `json:"diff"`
+	}
+
+	req := DiffPromptReq{}
+	rsp := DiffPromptRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := checkPromptName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	from, err := ReadPrompt(req.KB, req.Name, req.From)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	to, err := ReadPrompt(req.KB, req.Name, req.To)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	versionName := func(version string) string {
+		if version == "" {
+			return req.Name + " (current)"
+		}
+		return req.Name + "@" + version
+	}
+
+	diff, err := unifiedDiff(versionName(req.From), versionName(req.To), from, to, 3)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Diff = diff
+	c.JSON(http.StatusOK, rsp)
+}
+
+// RollbackPrompt 回滚到指定版本，也用于激活调优生成的版本
+func (pa *PromptApi) RollbackPrompt(c *gin.Context) {
+	type RollbackPromptReq struct {
+		KB      string `json:"kb"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	type RollbackPromptRsp struct {
+		BaseRsp
+		Errors []string `json:"errors,omitempty"`
+	}
+
+	req := RollbackPromptReq{}
+	rsp := RollbackPromptRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := checkPromptName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	errs, err := ActivatePromptVersion(req.KB, req.Name, req.Version)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if len(errs) > 0 {
+		rsp.Code = -1
+		rsp.Msg = "invalid prompt: " + strings.Join(errs, "; ")
+		rsp.Errors = errs
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"graphraggo/internal/global"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	JobPromptTune = "prompt-tune"
+)
+
+var selectionMethods = []string{"random", "top", "all", "auto"}
+
+// PromptTuneOptions graphrag prompt-tune 参数
+type PromptTuneOptions struct {
+	Domain              string `json:"domain"`                // 领域描述，为空时由大模型推断
+	Language            string `json:"language"`              // 提示词语言，如 Chinese
+	SelectionMethod     string `json:"selection_method"`      // 文本块选择方式：random、top、all、auto
+	Limit               int    `json:"limit"`                 // random、top 方式下选择的文本块数量
+	ChunkSize           int    `json:"chunk_size"`            // 文本块大小
+	MaxTokens           int    `json:"max_tokens"`            // 生成提示词的最大 token 数
+	NSubsetMax          int    `json:"n_subset_max"`          // auto 方式下参与聚类的文本块数量
+	K                   int    `json:"k"`                     // auto 方式下选择的文本块数量
+	MinExamplesRequired int    `json:"min_examples_required"` // 实体抽取提示词最少示例数
+	DiscoverEntityTypes *bool  `json:"discover_entity_types"` // 是否由大模型发现实体类型
+}
+
+// PromptTuneResult 调优生成的提示词版本
+type PromptTuneResult struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (o *PromptTuneOptions) validate() error {
+	if o.SelectionMethod != "" && !slices.Contains(selectionMethods, o.SelectionMethod) {
+		return fmt.Errorf("selection_method '%s' is not one of %v", o.SelectionMethod, selectionMethods)
+	}
+	for name, v := range map[string]int{
+		"limit":                 o.Limit,
+		"chunk_size":            o.ChunkSize,
+		"max_tokens":            o.MaxTokens,
+		"n_subset_max":          o.NSubsetMax,
+		"k":                     o.K,
+		"min_examples_required": o.MinExamplesRequired,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// args 转换为命令行参数，未设置的参数使用 graphrag 默认值
+func (o *PromptTuneOptions) args() []string {
+	args := []string{}
+	if o.Domain != "" {
+		args = append(args, "--domain", o.Domain)
+	}
+	if o.Language != "" {
+		args = append(args, "--language", o.Language)
+	}
+	if o.SelectionMethod != "" {
+		args = append(args, "--selection-method", o.SelectionMethod)
+	}
+	flags := []struct {
+		name  string
+		value int
+	}{
+		{"--limit", o.Limit},
+		{"--chunk-size", o.ChunkSize},
+		{"--max-tokens", o.MaxTokens},
+		{"--n-subset-max", o.NSubsetMax},
+		{"--k", o.K},
+		{"--min-examples-required", o.MinExamplesRequired},
+	}
+	for _, f := range flags {
+		if f.value > 0 {
+			args = append(args, f.name, strconv.Itoa(f.value))
+		}
+	}
+	if o.DiscoverEntityTypes != nil {
+		if *o.DiscoverEntityTypes {
+			args = append(args, "--discover-entity-types")
+		} else {
+			args = append(args, "--no-discover-entity-types")
+		}
+	}
+	return args
+}
+
+// runPromptTune 执行 graphrag prompt-tune，并将生成的提示词保存为未生效的新版本
+func runPromptTune(ctx context.Context, job *Job, kb string, opts PromptTuneOptions) (any, error) {
+	// 输出到临时目录，避免直接覆盖知识库的 prompts 目录
+	output, err := os.MkdirTemp("", "prompt-tune-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(output)
+
+	args := []string{"-m", "graphrag", "prompt-tune",
+		"--root", kbPath(kb),
+		"--output", output,
+	}
+	args = append(args, opts.args()...)
+
+	job.SetProgress(0.1, "running graphrag prompt-tune")
+	cmd := exec.CommandContext(ctx, global.PythonPath, args...)
+	if err := runJobCommand(job, cmd); err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(output)
+	if err != nil {
+		return nil, err
+	}
+
+	job.SetProgress(0.9, "saving prompt versions")
+	results := []PromptTuneResult{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".txt" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(output, file.Name()))
+		if err != nil {
+			return results, err
+		}
+
+		errs, warnings := checkPromptPlaceholders(file.Name(), string(content))
+		v, err := SavePromptVersion(kb, file.Name(), string(content), PromptSourceTune,
+			fmt.Sprintf("job %s", job.ID), false)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, PromptTuneResult{
+			Name:     file.Name(),
+			Version:  v.Version,
+			Warnings: append(errs, warnings...),
+		})
+	}
+
+	if len(results) == 0 {
+		return results, fmt.Errorf("prompt-tune generated no prompts")
+	}
+	return results, nil
+}
+
+// TunePrompt 针对知识库领域自动调优提示词
+// 调优结果保存为新版本，需通过 /prompt/diff 审阅、/prompt/activate 生效
+func (pa *PromptApi) TunePrompt(c *gin.Context) {
+	type TunePromptReq struct {
+		KB string `json:"kb"`
+		PromptTuneOptions
+	}
+	type TunePromptRsp struct {
+		BaseRsp
+		JobID string `json:"job_id"`
+	}
+
+	req := TunePromptReq{}
+	rsp := TunePromptRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if err := req.PromptTuneOptions.validate(); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	opts := req.PromptTuneOptions
+	job, err := StartJob(JobPromptTune, req.KB, func(ctx context.Context, job *Job) (any, error) {
+		return runPromptTune(ctx, job, req.KB, opts)
+	})
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusConflict, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.JobID = job.ID
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mozillazg/go-pinyin"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+	snippetRunes       = 80
+)
+
+// 匹配方式，按得分从高到低
+const (
+	MatchExact           = "exact"
+	MatchPrefix          = "prefix"
+	MatchSubstring       = "substring"
+	MatchPinyin          = "pinyin" // 全拼或首字母前缀
+	MatchPinyinSubstring = "pinyin_substring"
+	MatchFuzzy           = "fuzzy"
+	MatchDescription     = "description"
+)
+
+var matchScores = map[string]float64{
+	MatchExact:           100,
+	MatchPrefix:          80,
+	MatchSubstring:       60,
+	MatchPinyin:          50,
+	MatchPinyinSubstring: 40,
+	MatchFuzzy:           30,
+	MatchDescription:     10,
+}
+
+// searchEntry 实体的搜索索引项
+type searchEntry struct {
+	node        *GraphNode
+	title       string // 归一化后的 title
+	description string // 归一化后的描述
+	pinyin      string // title 的全拼，不含空格
+	initials    string // title 的拼音首字母
+}
+
+// SearchIndex 实体搜索索引
+type SearchIndex struct {
+	entries []searchEntry
+}
+
+// EntityMatch 实体搜索结果
+type EntityMatch struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Type    string  `json:"type"`
+	Degree  int     `json:"degree"`
+	Match   string  `json:"match"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// searchIndexCache 索引版本的搜索索引，图重新构建后重新构建
+type searchIndexCache struct {
+	graph *Graph
+	index *SearchIndex
+}
+
+var (
+	searchIndexes  = map[string]searchIndexCache{}
+	searchIndexMux sync.Mutex
+)
+
+// normalizeSearch 归一化：NFKC（全角转半角）、小写、繁体转简体、去除首尾空白
+func normalizeSearch(s string) string {
+	return strings.TrimSpace(toSimplified(strings.ToLower(norm.NFKC.String(s))))
+}
+
+var pinyinArgs = pinyin.NewArgs()
+
+// toPinyin 获取全拼与首字母，汉字以外的字母与数字保留，其他字符忽略
+func toPinyin(s string) (full, initials string) {
+	var f, i strings.Builder
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			if p := pinyin.SinglePinyin(r, pinyinArgs); len(p) > 0 && p[0] != "" {
+				f.WriteString(p[0])
+				i.WriteByte(p[0][0])
+			}
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			f.WriteRune(unicode.ToLower(r))
+			i.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return f.String(), i.String()
+}
+
+func hasHan(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.Is(unicode.Han, r) }) >= 0
+}
+
+// BuildSearchIndex 由图中的实体构建搜索索引
+func BuildSearchIndex(g *Graph) *SearchIndex {
+	index := SearchIndex{entries: make([]searchEntry, 0, len(g.Nodes))}
+	for _, node := range g.Nodes {
+		title := normalizeSearch(node.Title)
+		full, initials := toPinyin(title)
+		index.entries = append(index.entries, searchEntry{
+			node:        node,
+			title:       title,
+			description: normalizeSearch(node.Description),
+			pinyin:      full,
+			initials:    initials,
+		})
+	}
+	sort.Slice(index.entries, func(i, j int) bool { return index.entries[i].node.Title < index.entries[j].node.Title })
+	return &index
+}
+
+// ReadSearchIndex 获取索引版本的实体搜索索引
+func ReadSearchIndex(kb, db string) (*SearchIndex, error) {
+	g, err := ReadGraph(kb, db)
+	if err != nil {
+		return nil, err
+	}
+
+	key := kb + "/" + db
+	if db == "" {
+		key = kb + "/" + defaultDB
+	}
+
+	searchIndexMux.Lock()
+	defer searchIndexMux.Unlock()
+
+	if cache, ok := searchIndexes[key]; ok && cache.graph == g {
+		return cache.index, nil
+	}
+	index := BuildSearchIndex(g)
+	searchIndexes[key] = searchIndexCache{graph: g, index: index}
+	return index, nil
+}
+
+// levenshtein 按字符计算编辑距离
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// fuzzyDistance 模糊匹配的编辑距离，同时与 title 及其等长前缀比较，便于输入过程中补全
+func fuzzyDistance(query, title []rune) int {
+	d := levenshtein(query, title)
+	if len(title) > len(query) {
+		d = min(d, levenshtein(query, title[:len(query)]))
+	}
+	return d
+}
+
+// snippet 截取描述中匹配位置附近的文本，没有匹配时取开头
+func snippet(description, normalized, query string) string {
+	runes := []rune(description)
+	start := 0
+	if i := strings.Index(normalized, query); i >= 0 && utf8.RuneCountInString(normalized) == len(runes) {
+		start = max(utf8.RuneCountInString(normalized[:i])-snippetRunes/4, 0)
+	}
+	end := min(start+snippetRunes, len(runes))
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+// match 计算实体与查询的匹配方式与得分，不匹配时返回空字符串
+func (e *searchEntry) match(query, queryPinyin string, queryRunes []rune, pinyinQuery bool) string {
+	switch {
+	case e.title == query:
+		return MatchExact
+	case strings.HasPrefix(e.title, query):
+		return MatchPrefix
+	case strings.Contains(e.title, query):
+		return MatchSubstring
+	}
+
+	// 拼音：字母输入匹配全拼或首字母，汉字输入按读音匹配（同音字、对照表以外的繁体字）
+	if queryPinyin != "" && (pinyinQuery || hasHan(query)) {
+		if strings.HasPrefix(e.pinyin, queryPinyin) || (pinyinQuery && strings.HasPrefix(e.initials, queryPinyin)) {
+			return MatchPinyin
+		}
+		if strings.Contains(e.pinyin, queryPinyin) {
+			return MatchPinyinSubstring
+		}
+	}
+
+	// 模糊：允许的编辑距离随查询长度增加
+	if len(queryRunes) >= 2 {
+		if fuzzyDistance(queryRunes, []rune(e.title)) <= max(1, len(queryRunes)/4) {
+			return MatchFuzzy
+		}
+	}
+
+	if strings.Contains(e.description, query) {
+		return MatchDescription
+	}
+	return ""
+}
+
+// Search 搜索实体，按匹配得分、度数排序
+func (index *SearchIndex) Search(q string, types []string, limit int) []EntityMatch {
+	query := normalizeSearch(q)
+	if query == "" {
+		return []EntityMatch{}
+	}
+	queryRunes := []rune(query)
+	queryPinyin, _ := toPinyin(query)
+	// 只包含字母与空格的输入视为拼音
+	pinyinQuery := strings.IndexFunc(query, func(r rune) bool { return !(r >= 'a' && r <= 'z' || r == ' ') }) < 0
+
+	matches := []EntityMatch{}
+	for i := range index.entries {
+		e := &index.entries[i]
+		if len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return strings.EqualFold(t, e.node.Type) }) {
+			continue
+		}
+		kind := e.match(query, queryPinyin, queryRunes, pinyinQuery)
+		if kind == "" {
+			continue
+		}
+		// 同一匹配方式下，title 越短越接近查询
+		score := matchScores[kind] - float64(utf8.RuneCountInString(e.title)-len(queryRunes))/100
+		matches = append(matches, EntityMatch{
+			ID:      e.node.ID,
+			Title:   e.node.Title,
+			Type:    e.node.Type,
+			Degree:  e.node.Degree,
+			Match:   kind,
+			Score:   score,
+			Snippet: snippet(e.node.Description, e.description, query),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Degree > matches[j].Degree
+	})
+	return matches[:min(limit, len(matches))]
+}
+
+// SearchEntities 搜索实体，支持前缀、子串、模糊、拼音与繁简匹配，适用于输入补全
+func (ga *GraphApi) SearchEntities(c *gin.Context) {
+	type SearchEntitiesReq struct {
+		KB    string   `json:"kb"`
+		DB    string   `json:"db"`
+		Query string   `json:"query"`
+		Types []string `json:"types"`
+		Limit int      `json:"limit"`
+	}
+	type SearchEntitiesRsp struct {
+		BaseRsp
+		Entities []EntityMatch `json:"entities"`
+	}
+
+	req := SearchEntitiesReq{}
+	rsp := SearchEntitiesRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	index, err := ReadSearchIndex(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Entities = index.Search(req.Query, req.Types, limitOf(req.Limit, defaultSearchLimit, maxSearchLimit))
+	c.JSON(http.StatusOK, rsp)
+}
+
+// evictSearchIndexes 删除匹配的索引版本的搜索索引
+func evictSearchIndexes(m cacheMatcher) {
+	searchIndexMux.Lock()
+	defer searchIndexMux.Unlock()
+	for key := range searchIndexes {
+		if m.matchKey(key) {
+			delete(searchIndexes, key)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+)
+
+const (
+	defaultSemanticTopK = 10
+	maxSemanticTopK     = 100
+)
+
+// 向量的来源：graphrag 开启 snapshots.embeddings 后输出的 embeddings.<name>.parquet，
+// 或 graphrag 0.4 之前输出表中的向量列
+var (
+	entityEmbeddings   = embeddingSource{name: "entity.description", table: TableEntities, column: "description_embedding"}
+	textUnitEmbeddings = embeddingSource{name: "text_unit.text", table: TableTextUnits, column: "text_embedding"}
+)
+
+type embeddingSource struct {
+	name   string
+	table  string
+	column string
+}
+
+// vectorIndex 内存中的向量索引，向量已归一化，余弦相似度即为内积
+type vectorIndex struct {
+	ids     []string
+	vectors [][]float32
+	dim     int
+}
+
+// vectorIndexCache 索引版本的向量索引，向量文件大小与修改时间不变时复用
+type vectorIndexCache struct {
+	size    int64
+	modTime time.Time
+	index   *vectorIndex
+}
+
+var (
+	vectorIndexes  = map[string]vectorIndexCache{}
+	vectorIndexMux sync.Mutex
+)
+
+var errNoVectorColumn = errors.New("vector column not found")
+
+// SemanticEntity 语义搜索的实体结果
+type SemanticEntity struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Degree      int     `json:"degree"`
+	Score       float64 `json:"score"` // 余弦相似度
+}
+
+// SemanticTextUnit 语义搜索的文本块结果
+type SemanticTextUnit struct {
+	ID          string   `json:"id"`
+	Text        string   `json:"text"`
+	DocumentIDs []string `json:"document_ids"`
+	Score       float64  `json:"score"`
+}
+
+// normalizeVector 转为单位向量，零向量返回 nil
+func normalizeVector(v []float32) []float32 {
+	norm := 0.0
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	u := make([]float32, len(v))
+	for i, x := range v {
+		u[i] = float32(float64(x) / norm)
+	}
+	return u
+}
+
+// forEachValue 依次读取列的每个值，列表列的每个元素为一个值，重复级别为 0 时表示新的一行
+func forEachValue(chunk parquet.ColumnChunk, do func(v parquet.Value) error) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	values := make([]parquet.Value, 1024)
+	for {
+		page, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r := page.Values()
+		for {
+			n, err := r.ReadValues(values)
+			for _, v := range values[:n] {
+				if err := do(v); err != nil {
+					parquet.Release(page)
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				parquet.Release(page)
+				return err
+			}
+		}
+		parquet.Release(page)
+	}
+}
+
+// readVectorIndex 读取 parquet 文件的 id 列与向量列构建向量索引，跳过没有向量的行。
+// 向量按列直接解码为 float32，不经过 readParquet 的 map 与 []any
+func readVectorIndex(path, column string) (*vectorIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pf, err := parquet.OpenFile(f, stat.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", stat.Name(), err)
+	}
+
+	idColumn, ok := pf.Schema().Lookup("id")
+	if !ok {
+		return nil, fmt.Errorf("%s has no id column", stat.Name())
+	}
+	// 列表列的叶子路径为 column.list.element 等，按第一段匹配
+	vectorColumn := slices.IndexFunc(pf.Schema().Columns(), func(path []string) bool { return path[0] == column })
+	if vectorColumn < 0 {
+		return nil, errNoVectorColumn
+	}
+
+	index := vectorIndex{}
+	for _, rg := range pf.RowGroups() {
+		chunks := rg.ColumnChunks()
+		ids := []string{}
+		err := forEachValue(chunks[idColumn.ColumnIndex], func(v parquet.Value) error {
+			ids = append(ids, string(v.ByteArray()))
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", stat.Name(), err)
+		}
+
+		vectors := [][]float32{}
+		err = forEachValue(chunks[vectorColumn], func(v parquet.Value) error {
+			if v.RepetitionLevel() == 0 {
+				vectors = append(vectors, nil)
+			}
+			if v.IsNull() {
+				return nil
+			}
+			last := &vectors[len(vectors)-1]
+			switch v.Kind() {
+			case parquet.Float:
+				*last = append(*last, v.Float())
+			case parquet.Double:
+				*last = append(*last, float32(v.Double()))
+			default:
+				return fmt.Errorf("column '%s' is not a float list", column)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", stat.Name(), err)
+		}
+		if len(ids) != len(vectors) {
+			return nil, fmt.Errorf("failed to read %s: %d ids but %d vectors", stat.Name(), len(ids), len(vectors))
+		}
+
+		for i, vector := range vectors {
+			v := normalizeVector(vector)
+			if v == nil {
+				continue
+			}
+			if index.dim == 0 {
+				index.dim = len(v)
+			}
+			if len(v) != index.dim {
+				return nil, fmt.Errorf("embedding dimensions are inconsistent: %d and %d", index.dim, len(v))
+			}
+			index.ids = append(index.ids, ids[i])
+			index.vectors = append(index.vectors, v)
+		}
+	}
+	if len(index.vectors) == 0 {
+		return nil, fmt.Errorf("column '%s' has no embeddings", column)
+	}
+	return &index, nil
+}
+
+// ReadVectorIndex 获取索引版本的向量索引，优先使用 embeddings 快照，没有时使用输出表中的向量列
+func ReadVectorIndex(kb, db string, src embeddingSource) (*vectorIndex, error) {
+	out, err := outputPath(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	path, column := filepath.Join(out, "embeddings."+src.name+".parquet"), "embedding"
+	if _, err := os.Stat(path); err != nil {
+		if path, err = tableFile(kb, db, src.table); err != nil {
+			return nil, err
+		}
+		column = src.column
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path + "#" + column
+	vectorIndexMux.Lock()
+	cache, ok := vectorIndexes[key]
+	vectorIndexMux.Unlock()
+	if ok && cache.size == stat.Size() && cache.modTime.Equal(stat.ModTime()) {
+		return cache.index, nil
+	}
+
+	// 读取不持有锁，不阻塞其他版本与已缓存的查询，同时读取同一文件时可能重复构建
+	index, err := readVectorIndex(path, column)
+	if errors.Is(err, errNoVectorColumn) {
+		return nil, fmt.Errorf("no %s embeddings in output, enable snapshots.embeddings in settings and index again", src.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vectorIndexMux.Lock()
+	vectorIndexes[key] = vectorIndexCache{size: stat.Size(), modTime: stat.ModTime(), index: index}
+	vectorIndexMux.Unlock()
+	return index, nil
+}
+
+// vectorHit 向量搜索结果
+type vectorHit struct {
+	id    string
+	score float64
+}
+
+// Search 获取与 query 余弦相似度最高的向量，keep 为空时不过滤
+func (index *vectorIndex) Search(query []float32, k int, keep func(id string) bool) ([]vectorHit, error) {
+	if len(query) != index.dim {
+		return nil, fmt.Errorf("query embedding has %d dimensions but index has %d, the embedding model may have changed since indexing", len(query), index.dim)
+	}
+	q := normalizeVector(query)
+	if q == nil {
+		return nil, fmt.Errorf("query embedding is zero")
+	}
+
+	hits := []vectorHit{}
+	for i, v := range index.vectors {
+		if keep != nil && !keep(index.ids[i]) {
+			continue
+		}
+		score := 0.0
+		for j := range v {
+			score += float64(v[j]) * float64(q[j])
+		}
+		hits = append(hits, vectorHit{id: index.ids[i], score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+	return hits[:min(k, len(hits))], nil
+}
+
+// SemanticSearchEntities 按描述向量搜索实体
+func SemanticSearchEntities(kb, db string, query []float32, types []string, k int) ([]SemanticEntity, error) {
+	index, err := ReadVectorIndex(kb, db, entityEmbeddings)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ReadGraph(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	nodes := map[string]*GraphNode{}
+	for _, node := range g.Nodes {
+		if node.ID != "" {
+			nodes[node.ID] = node
+		}
+	}
+
+	hits, err := index.Search(query, k, func(id string) bool {
+		node, ok := nodes[id]
+		return ok && (len(types) == 0 || slices.ContainsFunc(types, func(t string) bool { return strings.EqualFold(t, node.Type) }))
+	})
+	if err != nil {
+		return nil, err
+	}
+	entities := []SemanticEntity{}
+	for _, hit := range hits {
+		node := nodes[hit.id]
+		entities = append(entities, SemanticEntity{
+			ID:          node.ID,
+			Title:       node.Title,
+			Type:        node.Type,
+			Description: node.Description,
+			Degree:      node.Degree,
+			Score:       hit.score,
+		})
+	}
+	return entities, nil
+}
+
+// SemanticSearchTextUnits 按文本向量搜索文本块
+func SemanticSearchTextUnits(kb, db string, query []float32, k int) ([]SemanticTextUnit, error) {
+	index, err := ReadVectorIndex(kb, db, textUnitEmbeddings)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ReadOutputTable(kb, db, TableTextUnits)
+	if err != nil {
+		return nil, err
+	}
+	rows := map[string]map[string]any{}
+	for _, row := range t.Rows {
+		rows[stringOf(row["id"])] = row
+	}
+
+	hits, err := index.Search(query, k, func(id string) bool { return rows[id] != nil })
+	if err != nil {
+		return nil, err
+	}
+	units := []SemanticTextUnit{}
+	for _, hit := range hits {
+		row := rows[hit.id]
+		units = append(units, SemanticTextUnit{
+			ID:          hit.id,
+			Text:        stringOf(row["text"]),
+			DocumentIDs: stringsOf(row["document_ids"]),
+			Score:       hit.score,
+		})
+	}
+	return units, nil
+}
+
+// SemanticSearch 使用知识库配置的向量化模型获取查询的向量，搜索描述最相近的实体，可同时搜索文本块
+func (ga *GraphApi) SemanticSearch(c *gin.Context) {
+	type SemanticSearchReq struct {
+		KB        string   `json:"kb"`
+		DB        string   `json:"db"`
+		Query     string   `json:"query"`
+		Types     []string `json:"types"`
+		TopK      int      `json:"top_k"`
+		TextUnits bool     `json:"text_units"` // 是否同时搜索文本块
+	}
+	type SemanticSearchRsp struct {
+		BaseRsp
+		Entities  []SemanticEntity   `json:"entities"`
+		TextUnits []SemanticTextUnit `json:"text_units,omitempty"`
+	}
+
+	req := SemanticSearchReq{}
+	rsp := SemanticSearchRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		rsp.Code = -1
+		rsp.Msg = "query is empty"
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	// 先加载向量索引，没有向量时不必调用模型
+	if _, err := ReadVectorIndex(req.KB, req.DB, entityEmbeddings); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+
+	embedder, err := embedderOf(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	vectors, err := embedder.Embed(c.Request.Context(), []string{req.Query})
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadGateway, rsp)
+		return
+	}
+
+	k := limitOf(req.TopK, defaultSemanticTopK, maxSemanticTopK)
+	rsp.Entities, err = SemanticSearchEntities(req.KB, req.DB, vectors[0], req.Types, k)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if req.TextUnits {
+		rsp.TextUnits, err = SemanticSearchTextUnits(req.KB, req.DB, vectors[0], k)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusNotFound, rsp)
+			return
+		}
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// evictVectorIndexes 删除匹配的索引版本的向量索引
+func evictVectorIndexes(m cacheMatcher) {
+	vectorIndexMux.Lock()
+	defer vectorIndexMux.Unlock()
+	for key := range vectorIndexes {
+		if m.matchPath(key) {
+			delete(vectorIndexes, key)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+
+	"graphraggo/internal/global"
+
+	"github.com/gin-gonic/gin"
+	"github.com/parquet-go/parquet-go"
+)
+
+// hashEmbedder 确定性的本地向量化：单词与汉字按哈希累加到各维度，共享的词越多余弦相似度越高
+type hashEmbedder struct {
+	dim int
+}
+
+func (e hashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, e.dim)
+		tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) || unicode.Is(unicode.Han, r)
+		})
+		for _, r := range text {
+			if unicode.Is(unicode.Han, r) {
+				tokens = append(tokens, string(r))
+			}
+		}
+		for _, token := range tokens {
+			h := fnv.New32a()
+			h.Write([]byte(token))
+			sum := h.Sum32()
+			if sum&1 == 0 {
+				v[sum%uint32(e.dim)]++
+			} else {
+				v[sum%uint32(e.dim)]--
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+type testEntity struct {
+	ID                   string    `parquet:"id"`
+	Title                string    `parquet:"title"`
+	Type                 string    `parquet:"type"`
+	Description          string    `parquet:"description"`
+	DescriptionEmbedding []float64 `parquet:"description_embedding,list"`
+}
+
+type testRelationship struct {
+	ID     string  `parquet:"id"`
+	Source string  `parquet:"source"`
+	Target string  `parquet:"target"`
+	Weight float64 `parquet:"weight"`
+}
+
+type testTextUnit struct {
+	ID          string   `parquet:"id"`
+	Text        string   `parquet:"text"`
+	DocumentIDs []string `parquet:"document_ids,list"`
+}
+
+type testEmbedding struct {
+	ID        string    `parquet:"id"`
+	Embedding []float32 `parquet:"embedding,list"`
+}
+
+const testEmbeddingDim = 64
+
+var testDescriptions = []struct{ title, typ, description string }{
+	{"变压器", "EQUIPMENT", "电力变压器 用于 升压 降压"},
+	{"断路器", "EQUIPMENT", "高压断路器 开断 短路 电流"},
+	{"巡检规程", "DOCUMENT", "变电站 设备 巡检 周期 规程"},
+	{"调度中心", "ORGANIZATION", "电网 调度 运行 控制"},
+	{"无向量", "EQUIPMENT", ""},
+}
+
+// setupSemanticKB 在临时目录中创建知识库 test 的索引输出：实体表带 float64 向量列，文本块使用 float32 向量快照
+func setupSemanticKB(t *testing.T) (out string, embedder Embedder) {
+	t.Helper()
+	workDir := global.WorkDir
+	global.WorkDir = t.TempDir()
+	t.Cleanup(func() {
+		global.WorkDir = workDir
+		clear(outputTables)
+		clear(graphs)
+		clear(vectorIndexes)
+	})
+	out = kbPath("test", "output")
+	if err := os.MkdirAll(out, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	embedder = hashEmbedder{dim: testEmbeddingDim}
+	ctx := context.Background()
+	entities := []testEntity{}
+	for i, d := range testDescriptions {
+		e := testEntity{ID: string(rune('a' + i)), Title: d.title, Type: d.typ, Description: d.description}
+		if d.description != "" {
+			v, _ := embedder.Embed(ctx, []string{d.description})
+			for _, x := range v[0] {
+				e.DescriptionEmbedding = append(e.DescriptionEmbedding, float64(x))
+			}
+		}
+		entities = append(entities, e)
+	}
+	relationships := []testRelationship{
+		{ID: "r1", Source: "变压器", Target: "断路器", Weight: 1},
+		{ID: "r2", Source: "巡检规程", Target: "变压器", Weight: 1},
+		{ID: "r3", Source: "调度中心", Target: "断路器", Weight: 1},
+		{ID: "r4", Source: "无向量", Target: "调度中心", Weight: 1},
+	}
+	units := []testTextUnit{
+		{ID: "t1", Text: "变压器 每年 检修 一次", DocumentIDs: []string{"d1"}},
+		{ID: "t2", Text: "调度 运行 值班 制度", DocumentIDs: []string{"d2"}},
+	}
+	embeddings := []testEmbedding{}
+	for _, u := range units {
+		v, _ := embedder.Embed(ctx, []string{u.Text})
+		embeddings = append(embeddings, testEmbedding{ID: u.ID, Embedding: v[0]})
+	}
+
+	write := func(name string, err error) {
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write("entities", parquet.WriteFile(filepath.Join(out, "entities.parquet"), entities))
+	write("relationships", parquet.WriteFile(filepath.Join(out, "relationships.parquet"), relationships))
+	write("text_units", parquet.WriteFile(filepath.Join(out, "text_units.parquet"), units))
+	write("embeddings", parquet.WriteFile(filepath.Join(out, "embeddings.text_unit.text.parquet"), embeddings))
+	return out, embedder
+}
+
+func TestReadVectorIndex(t *testing.T) {
+	out, _ := setupSemanticKB(t)
+
+	// 输出表中的 float64 向量列，没有向量的行跳过
+	index, err := ReadVectorIndex("test", "", entityEmbeddings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.dim != testEmbeddingDim || len(index.ids) != 4 || len(index.vectors) != 4 {
+		t.Fatalf("got dim %d, %d ids, %d vectors, want %d, 4, 4", index.dim, len(index.ids), len(index.vectors), testEmbeddingDim)
+	}
+	for i, v := range index.vectors {
+		norm := 0.0
+		for _, x := range v {
+			norm += float64(x) * float64(x)
+		}
+		if math.Abs(norm-1) > 1e-5 {
+			t.Errorf("vector %s is not normalized: %v", index.ids[i], norm)
+		}
+	}
+	if cached, _ := ReadVectorIndex("test", "", entityEmbeddings); cached != index {
+		t.Errorf("index should be cached")
+	}
+
+	// float32 向量快照
+	units, err := ReadVectorIndex("test", "", textUnitEmbeddings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(units.ids, ",") != "t1,t2" {
+		t.Errorf("got ids %v, want t1, t2", units.ids)
+	}
+
+	// 快照更新后重新读取
+	rows := []testEmbedding{{ID: "t3", Embedding: []float32{1, 0}}}
+	if err := parquet.WriteFile(filepath.Join(out, "embeddings.text_unit.text.parquet"), rows); err != nil {
+		t.Fatal(err)
+	}
+	units, err = ReadVectorIndex("test", "", textUnitEmbeddings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if units.dim != 2 || strings.Join(units.ids, ",") != "t3" {
+		t.Errorf("got dim %d and ids %v after update, want 2 and t3", units.dim, units.ids)
+	}
+}
+
+func TestReadVectorIndexWithoutEmbeddings(t *testing.T) {
+	out, _ := setupSemanticKB(t)
+	if err := os.Remove(filepath.Join(out, "embeddings.text_unit.text.parquet")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadVectorIndex("test", "", textUnitEmbeddings)
+	if err == nil || !strings.Contains(err.Error(), "snapshots.embeddings") {
+		t.Errorf("got error %v, want a hint to enable snapshots.embeddings", err)
+	}
+}
+
+func TestVectorIndexSearch(t *testing.T) {
+	_, embedder := setupSemanticKB(t)
+	index, err := ReadVectorIndex("test", "", entityEmbeddings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _ := embedder.Embed(context.Background(), []string{"电力变压器 升压"})
+
+	hits, err := index.Search(query[0], 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].id != "a" || hits[0].score < hits[1].score {
+		t.Errorf("got hits %v, want entity a first", hits)
+	}
+
+	hits, err = index.Search(query[0], 10, func(id string) bool { return id != "a" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 3 {
+		t.Errorf("got %d hits, want 3", len(hits))
+	}
+	for _, hit := range hits {
+		if hit.id == "a" {
+			t.Errorf("entity a should be filtered")
+		}
+	}
+
+	if _, err := index.Search([]float32{1, 2}, 10, nil); err == nil {
+		t.Errorf("query with different dimensions should fail")
+	}
+	if _, err := index.Search(make([]float32, testEmbeddingDim), 10, nil); err == nil {
+		t.Errorf("zero query should fail")
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	_, embedder := setupSemanticKB(t)
+	embedderOf = func(string) (Embedder, error) { return embedder, nil }
+	t.Cleanup(func() { embedderOf = newKBEmbedder })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	(&GraphApi{}).Register(r.Group("/api"))
+
+	body := `{"kb": "test", "query": "变电站设备巡检", "types": ["document", "equipment"], "top_k": 2, "text_units": true}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/graph/semantic_search", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body.String())
+	}
+
+	rsp := struct {
+		BaseRsp
+		Entities  []SemanticEntity   `json:"entities"`
+		TextUnits []SemanticTextUnit `json:"text_units"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if len(rsp.Entities) != 2 || rsp.Entities[0].Title != "巡检规程" || rsp.Entities[0].Degree != 1 {
+		t.Errorf("got entities %+v, want 巡检规程 first", rsp.Entities)
+	}
+	for _, e := range rsp.Entities {
+		if e.Type == "ORGANIZATION" {
+			t.Errorf("entity %s should be filtered by type", e.Title)
+		}
+	}
+	if len(rsp.TextUnits) != 2 || len(rsp.TextUnits[0].DocumentIDs) != 1 {
+		t.Errorf("got text units %+v, want 2 with document ids", rsp.TextUnits)
+	}
+}
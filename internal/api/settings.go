@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	settingsFile = "settings.yaml"
+)
+
+type SettingsApi struct {
+}
+
+func (sa *SettingsApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/settings")
+
+	r.GET("", sa.GetSettings)
+	r.PUT("", sa.UpdateSettings)
+	r.PATCH("", sa.PatchSettings)
+}
+
+// loadSettingsNode 读取知识库 settings.yaml，保留注释
+func loadSettingsNode(kb string) (*yaml.Node, error) {
+	data, err := os.ReadFile(kbPath(kb, settingsFile))
+	if err != nil {
+		return nil, err
+	}
+	return parseSettingsNode(data)
+}
+
+func parseSettingsNode(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("settings must be a yaml mapping")
+	}
+	return doc, nil
+}
+
+// encodeSettingsNode 将配置节点序列化为 yaml
+func encodeSettingsNode(doc *yaml.Node) ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	// yaml.v3 不保留空行，在顶层注释块前补回空行便于阅读
+	lines := strings.Split(buf.String(), "\n")
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if i > 0 && strings.HasPrefix(line, "#") {
+			prev := lines[i-1]
+			if prev != "" && !strings.HasPrefix(prev, "#") {
+				out = append(out, "")
+			}
+		}
+		out = append(out, line)
+	}
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// checkSettingsNode 检查未知键、类型与取值范围
+func checkSettingsNode(doc *yaml.Node) (*GraphRAGConfig, []string) {
+	errs := []string{}
+	for _, key := range unknownKeys(doc, reflect.TypeOf(GraphRAGConfig{}), "") {
+		errs = append(errs, fmt.Sprintf("%s: unknown key", key))
+	}
+
+	cfg := GraphRAGConfig{}
+	if err := doc.Decode(&cfg); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			errs = append(errs, typeErr.Errors...)
+		} else {
+			errs = append(errs, err.Error())
+		}
+		return nil, errs
+	}
+
+	errs = append(errs, cfg.Validate()...)
+	return &cfg, errs
+}
+
+// ReadSettings 获取知识库配置
+func ReadSettings(kb string) (*GraphRAGConfig, error) {
+	doc, err := loadSettingsNode(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := GraphRAGConfig{}
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// WriteSettings 校验并保存知识库配置，返回校验错误
+func WriteSettings(kb string, doc *yaml.Node) (*GraphRAGConfig, []string, error) {
+	cfg, errs := checkSettingsNode(doc)
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	data, err := encodeSettingsNode(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeFileAtomic(kbPath(kb, settingsFile), data); err != nil {
+		return nil, nil, err
+	}
+	return cfg, nil, nil
+}
+
+// PatchSettingsValues 以 merge patch 方式修改知识库配置，值为 null 的键会被删除
+func PatchSettingsValues(kb string, values map[string]any) (*GraphRAGConfig, []string, error) {
+	doc, err := loadSettingsNode(kb)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src := &yaml.Node{}
+	if err := src.Encode(values); err != nil {
+		return nil, nil, err
+	}
+	mergeNode(doc.Content[0], src, false)
+
+	return WriteSettings(kb, doc)
+}
+
+// mergeNode 将 src 合并到 dst 中，尽量保留 dst 原有的注释与风格
+// replace 为 true 时删除 src 中不存在的键，否则删除 src 中值为 null 的键
+func mergeNode(dst, src *yaml.Node, replace bool) {
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		seen[key.Value] = true
+
+		idx := -1
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				idx = j
+				break
+			}
+		}
+
+		if !replace && value.Tag == "!!null" {
+			if idx != -1 {
+				dst.Content = append(dst.Content[:idx], dst.Content[idx+2:]...)
+			}
+			continue
+		}
+
+		if idx == -1 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+
+		old := dst.Content[idx+1]
+		if old.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeNode(old, value, replace)
+			continue
+		}
+
+		if old.Kind == value.Kind && old.Kind != yaml.ScalarNode {
+			value.Style = old.Style
+		}
+		value.HeadComment = old.HeadComment
+		value.LineComment = old.LineComment
+		value.FootComment = old.FootComment
+		dst.Content[idx+1] = value
+	}
+
+	if !replace {
+		return
+	}
+	content := []*yaml.Node{}
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		if seen[dst.Content[i].Value] {
+			content = append(content, dst.Content[i], dst.Content[i+1])
+		}
+	}
+	dst.Content = content
+}
+
+// GetSettings 获取知识库配置
+func (sa *SettingsApi) GetSettings(c *gin.Context) {
+	type GetSettingsRsp struct {
+		BaseRsp
+		Settings *GraphRAGConfig `json:"settings"`
+		Content  string          `json:"content"`
+	}
+
+	rsp := GetSettingsRsp{}
+	kb := c.Query("kb")
+	if err := checkKB(kb); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	data, err := os.ReadFile(kbPath(kb, settingsFile))
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	cfg := GraphRAGConfig{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Settings = &cfg
+	rsp.Content = string(data)
+	c.JSON(http.StatusOK, rsp)
+}
+
+type UpdateSettingsRsp struct {
+	BaseRsp
+	Errors   []string        `json:"errors,omitempty"`
+	Settings *GraphRAGConfig `json:"settings,omitempty"`
+}
+
+// UpdateSettings 整体替换知识库配置
+// 可传入 settings（JSON 对象）或 content（yaml 原文），
+// 传入 settings 时会按键合并到原文件，尽量保留注释
+func (sa *SettingsApi) UpdateSettings(c *gin.Context) {
+	type UpdateSettingsReq struct {
+		KB       string         `json:"kb"`
+		Settings map[string]any `json:"settings"`
+		Content  string         `json:"content"`
+	}
+
+	req := UpdateSettingsReq{}
+	rsp := UpdateSettingsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	var doc *yaml.Node
+	var err error
+	switch {
+	case req.Content != "":
+		doc, err = parseSettingsNode([]byte(req.Content))
+	case req.Settings != nil:
+		doc, err = loadSettingsNode(req.KB)
+		if err == nil {
+			src := &yaml.Node{}
+			if err = src.Encode(req.Settings); err == nil {
+				mergeNode(doc.Content[0], src, true)
+			}
+		}
+	default:
+		err = fmt.Errorf("settings or content is required")
+	}
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cfg, errs, err := WriteSettings(req.KB, doc)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if len(errs) > 0 {
+		rsp.Code = -1
+		rsp.Msg = "invalid settings: " + strings.Join(errs, "; ")
+		rsp.Errors = errs
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Settings = cfg
+	c.JSON(http.StatusOK, rsp)
+}
+
+// PatchSettings 部分修改知识库配置，如 {"entity_extraction": {"entity_types": [...]}}
+func (sa *SettingsApi) PatchSettings(c *gin.Context) {
+	type PatchSettingsReq struct {
+		KB       string         `json:"kb"`
+		Settings map[string]any `json:"settings"`
+	}
+
+	req := PatchSettingsReq{}
+	rsp := UpdateSettingsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkKB(req.KB); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	cfg, errs, err := PatchSettingsValues(req.KB, req.Settings)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	if len(errs) > 0 {
+		rsp.Code = -1
+		rsp.Msg = "invalid settings: " + strings.Join(errs, "; ")
+		rsp.Errors = errs
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Settings = cfg
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"fmt"
+	"math/bits"
+	"net/http"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	topCentralEntities = 20
+	pageRankDamping    = 0.85
+	pageRankIterations = 50
+)
+
+// NameCount 按名称计数
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Distribution 数值的分布
+type Distribution struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// DegreeBucket 度数分布的区间 [Min, Max]
+type DegreeBucket struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// CentralEntity 中心度较高的实体
+type CentralEntity struct {
+	Title    string  `json:"title"`
+	Type     string  `json:"type"`
+	Degree   int     `json:"degree"`
+	PageRank float64 `json:"pagerank"`
+}
+
+// GraphStats 索引版本的图统计，用于检查抽取结果是否合理
+type GraphStats struct {
+	Entities          int             `json:"entities"`
+	Relationships     int             `json:"relationships"`
+	EntityTypes       []NameCount     `json:"entity_types"`
+	RelationshipTypes []NameCount     `json:"relationship_types"` // 关系表没有 type 列时为两端实体类型
+	Degree            Distribution    `json:"degree"`
+	DegreeHistogram   []DegreeBucket  `json:"degree_histogram"`
+	Components        int             `json:"components"`
+	LargestComponent  int             `json:"largest_component"`
+	IsolatedNodes     int             `json:"isolated_nodes"`
+	CentralEntities   []CentralEntity `json:"central_entities"` // 按 PageRank 倒序
+
+	CommunityLevels []NameCount `json:"community_levels"` // 各层级的社区数，没有社区表时为空
+
+	Documents            int          `json:"documents"`
+	TextUnits            int          `json:"text_units"`
+	TextUnitsPerDocument Distribution `json:"text_units_per_document"`
+	DocumentTextUnits    []NameCount  `json:"document_text_units"` // 每个文档的文本块数，按数量倒序
+
+	EntityDescriptionLength       float64 `json:"entity_description_length"` // 平均字符数
+	RelationshipDescriptionLength float64 `json:"relationship_description_length"`
+}
+
+// StatsComparison 两个索引版本同一指标的对比
+type StatsComparison struct {
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Compare float64 `json:"compare"`
+	Delta   float64 `json:"delta"` // Value - Compare
+}
+
+// graphStatsCache 索引版本的图统计，图或输出表重新读取后重新计算
+type graphStatsCache struct {
+	graph  *Graph
+	tables [4]*OutputTable
+	stats  *GraphStats
+}
+
+var (
+	graphStats    = map[string]graphStatsCache{}
+	graphStatsMux sync.Mutex
+)
+
+// countsOf 计数按数量倒序、名称排序
+func countsOf(counts map[string]int) []NameCount {
+	list := make([]NameCount, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, NameCount{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// distributionOf 计算数值的分布，values 会被排序
+func distributionOf(values []float64) Distribution {
+	d := Distribution{}
+	if len(values) == 0 {
+		return d
+	}
+	sort.Float64s(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	d.Min, d.Max, d.Mean = values[0], values[len(values)-1], sum/float64(len(values))
+	if n := len(values); n%2 == 1 {
+		d.Median = values[n/2]
+	} else {
+		d.Median = (values[n/2-1] + values[n/2]) / 2
+	}
+	return d
+}
+
+// degreeHistogram 度数分布，区间为 0、1、2-3、4-7……
+func degreeHistogram(g *Graph) []DegreeBucket {
+	buckets := []DegreeBucket{}
+	for _, node := range g.Nodes {
+		i := bits.Len(uint(node.Degree))
+		for n := len(buckets); n <= i; n++ {
+			b := DegreeBucket{}
+			if n > 0 {
+				b = DegreeBucket{Min: 1 << (n - 1), Max: 1<<n - 1}
+			}
+			buckets = append(buckets, b)
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// components 计算连通分量的数量与最大连通分量的节点数
+func (g *Graph) components() (count, largest int) {
+	seen := map[string]bool{}
+	for title := range g.Nodes {
+		if seen[title] {
+			continue
+		}
+		count++
+		size := 0
+		queue := []string{title}
+		seen[title] = true
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			size++
+			for _, i := range g.adj[cur] {
+				if other := g.other(g.Edges[i], cur); !seen[other] {
+					seen[other] = true
+					queue = append(queue, other)
+				}
+			}
+		}
+		largest = max(largest, size)
+	}
+	return count, largest
+}
+
+// pageRank 计算无向图的 PageRank，孤立节点的值平均分配给所有节点
+func (g *Graph) pageRank() map[string]float64 {
+	n := float64(len(g.Nodes))
+	rank := map[string]float64{}
+	for title := range g.Nodes {
+		rank[title] = 1 / n
+	}
+	for range pageRankIterations {
+		next := map[string]float64{}
+		dangling := 0.0
+		for title, r := range rank {
+			edges := g.adj[title]
+			if len(edges) == 0 {
+				dangling += r
+				continue
+			}
+			for _, i := range edges {
+				next[g.other(g.Edges[i], title)] += r / float64(len(edges))
+			}
+		}
+		for title := range g.Nodes {
+			next[title] = (1-pageRankDamping)/n + pageRankDamping*(next[title]+dangling/n)
+		}
+		rank = next
+	}
+	return rank
+}
+
+// BuildGraphStats 由图与输出表计算统计，社区表、文本块表与文档表可以为空
+func BuildGraphStats(g *Graph, relationships, communities, textUnits, documents *OutputTable) *GraphStats {
+	s := GraphStats{
+		Entities:        len(g.Nodes),
+		Relationships:   len(g.Edges),
+		CentralEntities: []CentralEntity{},
+		CommunityLevels: []NameCount{},
+	}
+
+	entityTypes := map[string]int{}
+	degrees := []float64{}
+	descriptionLength := 0
+	for _, node := range g.Nodes {
+		entityTypes[node.Type]++
+		degrees = append(degrees, float64(node.Degree))
+		descriptionLength += utf8.RuneCountInString(node.Description)
+		if node.Degree == 0 {
+			s.IsolatedNodes++
+		}
+	}
+	s.EntityTypes = countsOf(entityTypes)
+	s.Degree = distributionOf(degrees)
+	s.DegreeHistogram = degreeHistogram(g)
+	s.Components, s.LargestComponent = g.components()
+	if s.Entities > 0 {
+		s.EntityDescriptionLength = float64(descriptionLength) / float64(s.Entities)
+	}
+
+	types := map[string]string{}
+	if relationships != nil {
+		for _, row := range relationships.Rows {
+			types[stringOf(row["id"])] = stringOf(row["type"])
+		}
+	}
+	relationshipTypes := map[string]int{}
+	descriptionLength = 0
+	for _, e := range g.Edges {
+		t := types[e.ID]
+		if t == "" {
+			t = g.Nodes[e.Source].Type + " - " + g.Nodes[e.Target].Type
+		}
+		relationshipTypes[t]++
+		descriptionLength += utf8.RuneCountInString(e.Description)
+	}
+	s.RelationshipTypes = countsOf(relationshipTypes)
+	if s.Relationships > 0 {
+		s.RelationshipDescriptionLength = float64(descriptionLength) / float64(s.Relationships)
+	}
+
+	if len(g.Nodes) > 0 {
+		rank := g.pageRank()
+		for title, node := range g.Nodes {
+			s.CentralEntities = append(s.CentralEntities, CentralEntity{
+				Title:    title,
+				Type:     node.Type,
+				Degree:   node.Degree,
+				PageRank: rank[title],
+			})
+		}
+		sort.Slice(s.CentralEntities, func(i, j int) bool {
+			if s.CentralEntities[i].PageRank != s.CentralEntities[j].PageRank {
+				return s.CentralEntities[i].PageRank > s.CentralEntities[j].PageRank
+			}
+			return s.CentralEntities[i].Title < s.CentralEntities[j].Title
+		})
+		s.CentralEntities = s.CentralEntities[:min(topCentralEntities, len(s.CentralEntities))]
+	}
+
+	if communities != nil {
+		levels := map[string]int{}
+		for _, row := range communities.Rows {
+			levels[fmt.Sprint(intOf(row["level"]))]++
+		}
+		for level, count := range levels {
+			s.CommunityLevels = append(s.CommunityLevels, NameCount{Name: level, Count: count})
+		}
+		sort.Slice(s.CommunityLevels, func(i, j int) bool {
+			return compareValues(numberOf(s.CommunityLevels[i].Name), numberOf(s.CommunityLevels[j].Name)) < 0
+		})
+	}
+
+	// 文档的文本块优先使用文档表的 text_unit_ids，没有时使用文本块表的 document_ids
+	perDocument := map[string]int{}
+	if documents != nil {
+		for _, row := range documents.Rows {
+			title := stringOf(row["title"])
+			if title == "" {
+				title = stringOf(row["id"])
+			}
+			ids, _ := row["text_unit_ids"].([]any)
+			perDocument[title] += len(ids)
+		}
+	}
+	if textUnits != nil {
+		s.TextUnits = len(textUnits.Rows)
+		if len(perDocument) == 0 {
+			for _, row := range textUnits.Rows {
+				for _, id := range stringsOf(row["document_ids"]) {
+					perDocument[id]++
+				}
+			}
+		}
+	}
+	s.Documents = len(perDocument)
+	s.DocumentTextUnits = countsOf(perDocument)
+	counts := []float64{}
+	for _, count := range perDocument {
+		counts = append(counts, float64(count))
+	}
+	s.TextUnitsPerDocument = distributionOf(counts)
+	return &s
+}
+
+// ReadGraphStats 获取索引版本的图统计
+func ReadGraphStats(kb, db string) (*GraphStats, error) {
+	g, err := ReadGraph(kb, db)
+	if err != nil {
+		return nil, err
+	}
+	tables := [4]*OutputTable{}
+	for i, table := range []string{TableRelationships, TableCommunities, TableTextUnits, TableDocuments} {
+		if t, err := ReadOutputTable(kb, db, table); err == nil {
+			tables[i] = t
+		}
+	}
+
+	key := kb + "/" + db
+	if db == "" {
+		key = kb + "/" + defaultDB
+	}
+
+	graphStatsMux.Lock()
+	defer graphStatsMux.Unlock()
+
+	if cache, ok := graphStats[key]; ok && cache.graph == g && cache.tables == tables {
+		return cache.stats, nil
+	}
+	s := BuildGraphStats(g, tables[0], tables[1], tables[2], tables[3])
+	graphStats[key] = graphStatsCache{graph: g, tables: tables, stats: s}
+	return s, nil
+}
+
+// scalars 统计中可以直接对比的数值
+func (s *GraphStats) scalars() []StatsComparison {
+	values := []StatsComparison{
+		{Name: "entities", Value: float64(s.Entities)},
+		{Name: "relationships", Value: float64(s.Relationships)},
+		{Name: "degree.mean", Value: s.Degree.Mean},
+		{Name: "degree.median", Value: s.Degree.Median},
+		{Name: "degree.max", Value: s.Degree.Max},
+		{Name: "components", Value: float64(s.Components)},
+		{Name: "largest_component", Value: float64(s.LargestComponent)},
+		{Name: "isolated_nodes", Value: float64(s.IsolatedNodes)},
+		{Name: "documents", Value: float64(s.Documents)},
+		{Name: "text_units", Value: float64(s.TextUnits)},
+		{Name: "text_units_per_document.mean", Value: s.TextUnitsPerDocument.Mean},
+		{Name: "entity_description_length", Value: s.EntityDescriptionLength},
+		{Name: "relationship_description_length", Value: s.RelationshipDescriptionLength},
+	}
+	for _, c := range s.CommunityLevels {
+		values = append(values, StatsComparison{Name: "communities.level_" + c.Name, Value: float64(c.Count)})
+	}
+	for _, c := range s.EntityTypes {
+		values = append(values, StatsComparison{Name: "entity_types." + c.Name, Value: float64(c.Count)})
+	}
+	return values
+}
+
+// CompareGraphStats 并列对比两个索引版本的统计，只在一方存在的指标另一方为 0
+func CompareGraphStats(s, compare *GraphStats) []StatsComparison {
+	result := s.scalars()
+	index := map[string]int{}
+	for i, c := range result {
+		index[c.Name] = i
+	}
+	for _, c := range compare.scalars() {
+		i, ok := index[c.Name]
+		if !ok {
+			result = append(result, StatsComparison{Name: c.Name})
+			i = len(result) - 1
+		}
+		result[i].Compare = c.Value
+	}
+	for i := range result {
+		result[i].Delta = result[i].Value - result[i].Compare
+	}
+	return result
+}
+
+// GetStats 获取索引版本的图统计，指定 compare 时与另一个索引版本并列对比
+func (ga *GraphApi) GetStats(c *gin.Context) {
+	type GetStatsReq struct {
+		KB      string `json:"kb"`
+		DB      string `json:"db"`
+		Compare string `json:"compare"`
+	}
+	type GetStatsRsp struct {
+		BaseRsp
+		Stats        *GraphStats       `json:"stats"`
+		CompareStats *GraphStats       `json:"compare_stats,omitempty"`
+		Comparison   []StatsComparison `json:"comparison,omitempty"`
+	}
+
+	req := GetStatsReq{}
+	rsp := GetStatsRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	stats, err := ReadGraphStats(req.KB, req.DB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusNotFound, rsp)
+		return
+	}
+	if req.Compare != "" {
+		rsp.CompareStats, err = ReadGraphStats(req.KB, req.Compare)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusNotFound, rsp)
+			return
+		}
+		rsp.Comparison = CompareGraphStats(stats, rsp.CompareStats)
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Stats = stats
+	c.JSON(http.StatusOK, rsp)
+}
+
+// evictGraphStats 删除匹配的索引版本的图统计
+func evictGraphStats(m cacheMatcher) {
+	graphStatsMux.Lock()
+	defer graphStatsMux.Unlock()
+	for key := range graphStats {
+		if m.matchKey(key) {
+			delete(graphStats, key)
+		}
+	}
+}
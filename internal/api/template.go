@@ -0,0 +1,484 @@
+package api
+
+import (
+	"fmt"
+	"graphraggo/internal/global"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	templateMetaFile = "template.yaml"
+	defaultTemplate  = "default" // 默认模板，新建知识库时复制其提示词，只读
+)
+
+// TemplateInfo 知识库模板信息
+type TemplateInfo struct {
+	Name        string    `yaml:"-" json:"name"`
+	Description string    `yaml:"description" json:"description"`
+	CreatedAt   time.Time `yaml:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `yaml:"updated_at" json:"updated_at"`
+	EntityTypes []string  `yaml:"-" json:"entity_types"`
+	Prompts     []string  `yaml:"-" json:"prompts"`
+}
+
+// templatePath 获取模板内文件的绝对路径
+func templatePath(name string, elem ...string) string {
+	parts := append([]string{global.WorkDir, global.TemplateDir, name}, elem...)
+	return filepath.Join(parts...)
+}
+
+// checkTemplate 校验模板是否存在
+func checkTemplate(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	info, err := os.Stat(templatePath(name))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("template '%s' not exists", name)
+	}
+	return nil
+}
+
+// checkTemplateWritable 校验模板是否存在且允许修改
+func checkTemplateWritable(name string) (int, error) {
+	if err := checkTemplate(name); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if name == defaultTemplate {
+		return http.StatusForbidden, fmt.Errorf("template '%s' is read-only", name)
+	}
+	return http.StatusOK, nil
+}
+
+// ReadTemplate 获取模板信息
+func ReadTemplate(name string) (*TemplateInfo, error) {
+	info := TemplateInfo{}
+	data, err := os.ReadFile(templatePath(name, templateMetaFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+	info.Name = name
+
+	cfg := GraphRAGConfig{}
+	data, err = os.ReadFile(templatePath(name, settingsFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.EntityExtraction != nil {
+		info.EntityTypes = cfg.EntityExtraction.EntityTypes
+	}
+
+	info.Prompts = []string{}
+	files, err := os.ReadDir(templatePath(name, promptDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".txt" {
+			info.Prompts = append(info.Prompts, file.Name())
+		}
+	}
+
+	return &info, nil
+}
+
+// ReadTemplates 获取所有模板
+func ReadTemplates() ([]TemplateInfo, error) {
+	files, err := os.ReadDir(filepath.Join(global.WorkDir, global.TemplateDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TemplateInfo{}, nil
+		}
+		return nil, err
+	}
+
+	templates := []TemplateInfo{}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		info, err := ReadTemplate(file.Name())
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *info)
+	}
+
+	return templates, nil
+}
+
+// applyTemplate 将模板的 settings、prompts、options 复制到知识库目录
+func applyTemplate(name, kb string) error {
+	if err := copyFile(templatePath(name, settingsFile), kbPath(kb, settingsFile)); err != nil {
+		return err
+	}
+	if _, err := os.Stat(templatePath(name, promptDir)); err == nil {
+		if err := copyDir(templatePath(name, promptDir), kbPath(kb, promptDir)); err != nil {
+			return err
+		}
+	} else if err := os.MkdirAll(kbPath(kb, promptDir), os.ModePerm); err != nil {
+		return err
+	}
+
+	opts, err := readOptionsFile(templatePath(name, optionsFile))
+	if err != nil {
+		return err
+	}
+	return WriteKBOptions(kb, opts)
+}
+
+// TemplateContent 模板内容，用于新建与修改模板
+type TemplateContent struct {
+	Description *string           `json:"description"`
+	Settings    string            `json:"settings"` // settings.yaml 原文
+	Prompts     map[string]string `json:"prompts"`  // 文件名 -> 内容，内容为空表示删除
+	Options     *KBOptions        `json:"options"`
+}
+
+// writeTemplate 校验并写入模板内容，未提供的部分保持不变
+func writeTemplate(name string, content *TemplateContent, created bool) error {
+	if content.Settings != "" {
+		doc, err := parseSettingsNode([]byte(content.Settings))
+		if err != nil {
+			return err
+		}
+		if _, errs := checkSettingsNode(doc); len(errs) > 0 {
+			return fmt.Errorf("invalid settings: %s", strings.Join(errs, "; "))
+		}
+	}
+	for prompt, text := range content.Prompts {
+		if err := checkPromptName(prompt); err != nil {
+			return err
+		}
+		if text == "" {
+			continue
+		}
+		if errs, _ := checkPromptPlaceholders(prompt, text); len(errs) > 0 {
+			return fmt.Errorf("invalid prompt '%s': %s", prompt, strings.Join(errs, "; "))
+		}
+	}
+	if content.Options != nil {
+		if err := content.Options.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if content.Settings != "" {
+		if err := writeFileAtomic(templatePath(name, settingsFile), []byte(content.Settings)); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(templatePath(name, promptDir), os.ModePerm); err != nil {
+		return err
+	}
+	for prompt, text := range content.Prompts {
+		path := templatePath(name, promptDir, prompt)
+		if text == "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := writeFileAtomic(path, []byte(text)); err != nil {
+			return err
+		}
+	}
+	if content.Options != nil {
+		if err := writeOptionsFile(templatePath(name, optionsFile), content.Options); err != nil {
+			return err
+		}
+	}
+
+	info, err := ReadTemplate(name)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if created {
+		info.CreatedAt = now
+	}
+	info.UpdatedAt = now
+	if content.Description != nil {
+		info.Description = *content.Description
+	}
+	data, err := yaml.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(templatePath(name, templateMetaFile), data)
+}
+
+type TemplateApi struct {
+}
+
+func (ta *TemplateApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/template")
+
+	r.GET("", ta.GetTemplates)
+	r.POST("/get", ta.GetTemplate)
+	r.POST("/add", ta.AddTemplate)
+	r.POST("/update", ta.UpdateTemplate)
+	r.POST("/delete", ta.DeleteTemplate)
+}
+
+// GetTemplates 获取所有模板
+func (ta *TemplateApi) GetTemplates(c *gin.Context) {
+	type GetTemplatesRsp struct {
+		BaseRsp
+		Templates []TemplateInfo `json:"templates"`
+	}
+
+	rsp := GetTemplatesRsp{}
+
+	templates, err := ReadTemplates()
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Templates = templates
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetTemplate 获取模板详情
+func (ta *TemplateApi) GetTemplate(c *gin.Context) {
+	type GetTemplateReq struct {
+		Name string `json:"name"`
+	}
+	type GetTemplateRsp struct {
+		BaseRsp
+		Template *TemplateInfo     `json:"template"`
+		Settings string            `json:"settings"`
+		Prompts  map[string]string `json:"prompts"`
+		Options  *KBOptions        `json:"options"`
+	}
+
+	req := GetTemplateReq{}
+	rsp := GetTemplateRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkTemplate(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	info, err := ReadTemplate(req.Name)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	settings, err := os.ReadFile(templatePath(req.Name, settingsFile))
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	prompts := map[string]string{}
+	for _, prompt := range info.Prompts {
+		data, err := os.ReadFile(templatePath(req.Name, promptDir, prompt))
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		prompts[prompt] = string(data)
+	}
+	opts, err := readOptionsFile(templatePath(req.Name, optionsFile))
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Template = info
+	rsp.Settings = string(settings)
+	rsp.Prompts = prompts
+	rsp.Options = opts
+	c.JSON(http.StatusOK, rsp)
+}
+
+// AddTemplate 新建模板
+// 以 from_kb 指定的知识库（为空时使用示例配置与提示词）为基础，再覆盖请求中提供的内容
+func (ta *TemplateApi) AddTemplate(c *gin.Context) {
+	type AddTemplateReq struct {
+		Name   string `json:"name"`
+		FromKB string `json:"from_kb"`
+		TemplateContent
+	}
+	type AddTemplateRsp struct {
+		BaseRsp
+	}
+
+	req := AddTemplateReq{}
+	rsp := AddTemplateRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkName(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if _, err := os.Stat(templatePath(req.Name)); err == nil {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("template '%s' already exists", req.Name)
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	settingFile, promptFolder, opts := global.ExampleSettingFile, global.ExamplePromptDir, DefaultKBOptions()
+	if req.FromKB != "" {
+		if err := checkKB(req.FromKB); err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusBadRequest, rsp)
+			return
+		}
+		kbOpts, err := ReadKBOptions(req.FromKB)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = err.Error()
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		settingFile, promptFolder, opts = kbPath(req.FromKB, settingsFile), kbPath(req.FromKB, promptDir), *kbOpts
+	}
+	if req.Options == nil {
+		req.Options = &opts
+	}
+
+	create := func() error {
+		if err := os.MkdirAll(templatePath(req.Name), os.ModePerm); err != nil {
+			return err
+		}
+		if err := copyFile(settingFile, templatePath(req.Name, settingsFile)); err != nil {
+			return err
+		}
+		if err := copyDir(promptFolder, templatePath(req.Name, promptDir)); err != nil {
+			return err
+		}
+		return writeTemplate(req.Name, &req.TemplateContent, true)
+	}
+	if err := create(); err != nil {
+		os.RemoveAll(templatePath(req.Name))
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UpdateTemplate 修改模板，未提供的部分保持不变
+func (ta *TemplateApi) UpdateTemplate(c *gin.Context) {
+	type UpdateTemplateReq struct {
+		Name string `json:"name"`
+		TemplateContent
+	}
+	type UpdateTemplateRsp struct {
+		BaseRsp
+	}
+
+	req := UpdateTemplateReq{}
+	rsp := UpdateTemplateRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if status, err := checkTemplateWritable(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(status, rsp)
+		return
+	}
+
+	if err := writeTemplate(req.Name, &req.TemplateContent, false); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// DeleteTemplate 删除模板
+func (ta *TemplateApi) DeleteTemplate(c *gin.Context) {
+	type DeleteTemplateReq struct {
+		Name string `json:"name"`
+	}
+	type DeleteTemplateRsp struct {
+		BaseRsp
+	}
+
+	req := DeleteTemplateReq{}
+	rsp := DeleteTemplateRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if status, err := checkTemplateWritable(req.Name); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(status, rsp)
+		return
+	}
+
+	if err := os.RemoveAll(templatePath(req.Name)); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,340 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"graphraggo/internal/global"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	trashDataDir  = "data"
+	trashMetaFile = "meta.json"
+)
+
+// 回收站条目类型
+const (
+	TrashKB     = "kb"
+	TrashOutput = "output"
+	TrashInput  = "input"
+)
+
+// TrashItem 回收站条目
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	KB        string    `json:"kb"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"` // 相对于 WorkDir 的原路径
+	Size      int64     `json:"size"`
+	DeletedBy string    `json:"deleted_by"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"` // 由删除时间与当前的保留期限计算，修改保留期限后对已删除的条目生效
+}
+
+var (
+	trashLock sync.Mutex
+	trashSeq  int
+)
+
+// trashPath 获取回收站内文件的绝对路径
+func trashPath(elem ...string) string {
+	parts := append([]string{global.WorkDir, global.TrashDir}, elem...)
+	return filepath.Join(parts...)
+}
+
+// operator 获取操作人，优先使用请求头 X-User，否则使用客户端 IP
+func operator(c *gin.Context) string {
+	if user := c.GetHeader("X-User"); user != "" {
+		return user
+	}
+	return c.ClientIP()
+}
+
+// dirSize 计算文件或文件夹大小
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// MoveToTrash 将文件或文件夹移入回收站
+func MoveToTrash(kind, kb, name, path, deletedBy string) (*TrashItem, error) {
+	trashLock.Lock()
+	defer trashLock.Unlock()
+
+	rel, err := filepath.Rel(global.WorkDir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	trashSeq++
+	item := TrashItem{
+		ID:        fmt.Sprintf("%s-%d", now.Format("20060102-150405"), trashSeq),
+		Kind:      kind,
+		KB:        kb,
+		Name:      name,
+		Path:      rel,
+		Size:      dirSize(path),
+		DeletedBy: deletedBy,
+		DeletedAt: now,
+		ExpiresAt: now.Add(global.TrashRetention),
+	}
+
+	if err := os.MkdirAll(trashPath(item.ID), os.ModePerm); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(trashPath(item.ID, trashMetaFile), data); err != nil {
+		os.RemoveAll(trashPath(item.ID))
+		return nil, err
+	}
+	if err := os.Rename(path, trashPath(item.ID, trashDataDir)); err != nil {
+		os.RemoveAll(trashPath(item.ID))
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func readTrashItem(id string) (*TrashItem, error) {
+	if err := checkName(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(trashPath(id, trashMetaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("trash item '%s' not exists", id)
+		}
+		return nil, err
+	}
+	item := TrashItem{}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	item.ExpiresAt = item.DeletedAt.Add(global.TrashRetention)
+	return &item, nil
+}
+
+// ReadTrash 获取回收站条目，kb 为空时返回全部
+func ReadTrash(kb string) ([]TrashItem, error) {
+	files, err := os.ReadDir(trashPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []TrashItem{}, nil
+		}
+		return nil, err
+	}
+
+	items := []TrashItem{}
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+		item, err := readTrashItem(file.Name())
+		if err != nil {
+			continue
+		}
+		if kb == "" || item.KB == kb {
+			items = append(items, *item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// RestoreTrash 将回收站条目恢复到原位置
+func RestoreTrash(id string) (*TrashItem, error) {
+	trashLock.Lock()
+	defer trashLock.Unlock()
+
+	item, err := readTrashItem(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := filepath.Join(global.WorkDir, item.Path)
+	if _, err := os.Stat(dst); err == nil {
+		return nil, fmt.Errorf("'%s' already exists", item.Path)
+	}
+	if item.Kind != TrashKB {
+		if err := checkKB(item.KB); err != nil {
+			return nil, fmt.Errorf("%s, restore the kb first", err.Error())
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(trashPath(id, trashDataDir), dst); err != nil {
+		return nil, err
+	}
+	return item, os.RemoveAll(trashPath(id))
+}
+
+// PurgeTrash 彻底删除回收站条目
+func PurgeTrash(id string) error {
+	trashLock.Lock()
+	defer trashLock.Unlock()
+
+	if _, err := readTrashItem(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(trashPath(id))
+}
+
+// PurgeExpiredTrash 彻底删除超过保留期限的条目，返回删除数量
+func PurgeExpiredTrash() (int, error) {
+	items, err := ReadTrash("")
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	now := time.Now()
+	for _, item := range items {
+		if now.Before(item.ExpiresAt) {
+			continue
+		}
+		if err := PurgeTrash(item.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+type TrashApi struct {
+}
+
+func (ta *TrashApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/trash")
+
+	r.POST("", ta.GetTrash)
+	r.POST("/restore", ta.RestoreTrash)
+	r.POST("/purge", ta.PurgeTrash)
+}
+
+// GetTrash 获取回收站条目
+func (ta *TrashApi) GetTrash(c *gin.Context) {
+	type GetTrashReq struct {
+		KB string `json:"kb"`
+	}
+	type GetTrashRsp struct {
+		BaseRsp
+		Items []TrashItem `json:"items"`
+	}
+
+	req := GetTrashReq{}
+	rsp := GetTrashRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	items, err := ReadTrash(req.KB)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Items = items
+	c.JSON(http.StatusOK, rsp)
+}
+
+// RestoreTrash 恢复回收站条目
+func (ta *TrashApi) RestoreTrash(c *gin.Context) {
+	type RestoreTrashReq struct {
+		IDs []string `json:"ids"`
+	}
+	type RestoreTrashRsp struct {
+		BaseRsp
+		Items []TrashItem `json:"items"`
+	}
+
+	req := RestoreTrashReq{}
+	rsp := RestoreTrashRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	rsp.Items = []TrashItem{}
+	for _, id := range req.IDs {
+		item, err := RestoreTrash(id)
+		if err != nil {
+			rsp.Code = -1
+			rsp.Msg = fmt.Sprintf("restore '%s' failed: %s", id, err.Error())
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+		rsp.Items = append(rsp.Items, *item)
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// PurgeTrash 彻底删除回收站条目
+func (ta *TrashApi) PurgeTrash(c *gin.Context) {
+	type PurgeTrashReq struct {
+		IDs []string `json:"ids"`
+	}
+	type PurgeTrashRsp struct {
+		BaseRsp
+	}
+
+	req := PurgeTrashReq{}
+	rsp := PurgeTrashRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	for _, id := range req.IDs {
+		if err := PurgeTrash(id); err != nil {
+			rsp.Code = -1
+			rsp.Msg = fmt.Sprintf("purge '%s' failed: %s", id, err.Error())
+			c.JSON(http.StatusInternalServerError, rsp)
+			return
+		}
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
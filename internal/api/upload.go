@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// 上传文件的编码
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+	EncodingBIG5    = "big5"
+	EncodingUnknown = "unknown"
+)
+
+// binaryMimeTypes 允许上传的二进制文件类型，其余文件必须为文本
+var binaryMimeTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+}
+
+// commonHanChars 常用汉字（含繁体），用于判断 GBK 与 BIG5 哪个解码结果更合理
+const commonHanChars = "的一是不了在人有我他这這个個们們中来來上大为為和国國地到以说說时時要就出会會可也你对對" +
+	"生能而子那得于着下自之年过過发發后後作里裡用道行所然家种種事成方多经經么麼去法学學如都同现現当當" +
+	"没沒动動面起看定天分还還进進好小部其些主样樣理心本前开開但因只从從想实實日电電力系统統设設备備"
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// encodingChunkSize 检测编码时每次读取的大小
+const encodingChunkSize = 1 << 20
+
+// UploadDetection 上传文件的检测结果与所做的修改
+type UploadDetection struct {
+	Size     int64    `json:"size"`
+	MimeType string   `json:"mime_type"`
+	Encoding string   `json:"encoding,omitempty"` // 文本文件的原始编码
+	Changes  []string `json:"changes,omitempty"`  // 对文件所做的修改，如转码、去除 BOM
+}
+
+// checkUploadSize 检查单文件大小与知识库输入文件总大小
+// exists 为同名文件的大小，覆盖上传时不计入总大小
+func checkUploadSize(opts *KBOptions, input string, size, exists int64) error {
+	if opts.Upload.MaxFileSize > 0 && size > opts.Upload.MaxFileSize {
+		return fmt.Errorf("文件大小 %d 超过限制 %d", size, opts.Upload.MaxFileSize)
+	}
+	if opts.Upload.MaxKBSize > 0 {
+		total := dirSize(input) - exists + size
+		if total > opts.Upload.MaxKBSize {
+			return fmt.Errorf("知识库输入文件总大小 %d 超过限制 %d", total, opts.Upload.MaxKBSize)
+		}
+	}
+	return nil
+}
+
+// InspectUpload 检测上传文件的类型，拒绝与扩展名不符的二进制文件，
+// 并将 GBK、GB18030、BIG5 编码的文本文件转为 UTF-8
+func InspectUpload(path, filename string) (*UploadDetection, error) {
+	mtype, err := mimetype.DetectFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 去掉 charset 参数，编码以 detectEncoding 的结果为准
+	mediaType, _, _ := strings.Cut(mtype.String(), ";")
+	detection := UploadDetection{
+		Size:     dirSize(path),
+		MimeType: mediaType,
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if expected, ok := binaryMimeTypes[ext]; ok {
+		if !mimeIs(mtype, expected) {
+			return nil, fmt.Errorf("文件内容为 %s，与扩展名 %s 不符", mediaType, ext)
+		}
+		return &detection, nil
+	}
+	if !mimeIs(mtype, "text/plain") {
+		return nil, fmt.Errorf("文件内容为 %s，不是文本文件", mediaType)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 文件可能很大，只读取开头判断 BOM，编码分块检测
+	r := bufio.NewReaderSize(f, encodingChunkSize)
+	bom := false
+	if prefix, _ := r.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		bom = true
+		r.Discard(len(utf8BOM))
+		detection.Changes = append(detection.Changes, "removed utf-8 bom")
+	}
+
+	detection.Encoding, err = detectFileEncoding(r)
+	if err != nil {
+		return nil, err
+	}
+	switch detection.Encoding {
+	case EncodingUTF8:
+	case EncodingUnknown:
+		return nil, fmt.Errorf("无法识别文件编码，请转换为 UTF-8 后上传")
+	default:
+		detection.Changes = append(detection.Changes,
+			fmt.Sprintf("transcoded %s to utf-8", detection.Encoding))
+	}
+	if len(detection.Changes) == 0 {
+		return &detection, nil
+	}
+
+	// 重新读取，去除 BOM 并转码后写回
+	offset := int64(0)
+	if bom {
+		offset = int64(len(utf8BOM))
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	counter := countingReader{r: encodingOf(detection.Encoding).NewDecoder().Reader(f)}
+	if err := writeFileAtomicFrom(path, &counter); err != nil {
+		return nil, fmt.Errorf("failed to transcode %s: %w", detection.Encoding, err)
+	}
+	detection.Size = counter.n
+	return &detection, nil
+}
+
+// mimeIs 判断文件类型是否为 expected 或其子类型，如 text/html 属于 text/plain
+func mimeIs(mtype *mimetype.MIME, expected string) bool {
+	for m := mtype; m != nil; m = m.Parent() {
+		if m.Is(expected) {
+			return true
+		}
+	}
+	return false
+}
+
+func encodingOf(name string) encoding.Encoding {
+	switch name {
+	case EncodingGBK:
+		return simplifiedchinese.GBK
+	case EncodingGB18030:
+		return simplifiedchinese.GB18030
+	case EncodingBIG5:
+		return traditionalchinese.Big5
+	}
+	return encoding.Nop
+}
+
+// detectEncoding 检测文本编码
+// 合法的 UTF-8 直接返回；否则分别按 GB18030 与 BIG5 解码，选择无效字符更少、常用字更多的编码
+func detectEncoding(data []byte) string {
+	if utf8.Valid(data) {
+		return EncodingUTF8
+	}
+
+	best, bestScore := EncodingUnknown, 0
+	for _, name := range []string{EncodingGB18030, EncodingBIG5} {
+		decoded, err := encodingOf(name).NewDecoder().Bytes(data)
+		if err != nil {
+			continue
+		}
+		score := 0
+		invalid := 0
+		for _, r := range string(decoded) {
+			switch {
+			case r == utf8.RuneError:
+				invalid++
+			case strings.ContainsRune(commonHanChars, r):
+				score++
+			}
+		}
+		// 少量无效字符可能是文件被截断，过多则说明编码不对
+		if invalid > 0 && invalid*100 > utf8.RuneCount(decoded) {
+			continue
+		}
+		score -= invalid * 10
+		if best == EncodingUnknown || score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+
+	if best == EncodingGB18030 && !hasGB18030FourByte(data) {
+		return EncodingGBK
+	}
+	return best
+}
+
+// detectFileEncoding 分块检测编码，内存中只保留一块：全部为合法 UTF-8 时返回 UTF-8，
+// 否则由第一处非 UTF-8 内容所在的块判断编码
+func detectFileEncoding(r io.Reader) (string, error) {
+	buf := make([]byte, encodingChunkSize)
+	carry := 0
+	for {
+		n, err := io.ReadFull(r, buf[carry:])
+		n += carry
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !eof {
+			return "", err
+		}
+
+		// 块末尾不完整的字符留到下一块
+		end := n
+		if !eof {
+			end = utf8Boundary(buf[:n])
+		}
+		if !utf8.Valid(buf[:end]) {
+			return detectEncoding(buf[:end]), nil
+		}
+		if eof {
+			return EncodingUTF8, nil
+		}
+		carry = copy(buf, buf[end:n])
+	}
+}
+
+// utf8Boundary 去掉末尾不完整的 UTF-8 字符后的长度
+func utf8Boundary(buf []byte) int {
+	n := len(buf)
+	for i := n - 1; i >= max(n-utf8.UTFMax, 0); i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:n]) {
+				return i
+			}
+			break
+		}
+	}
+	return n
+}
+
+// hasGB18030FourByte 判断是否包含 GB18030 的四字节编码，不包含时即为 GBK
+func hasGB18030FourByte(data []byte) bool {
+	for i := 0; i+3 < len(data); i++ {
+		b := data[i]
+		if b < 0x80 {
+			continue
+		}
+		if b >= 0x81 && b <= 0xFE && data[i+1] >= 0x30 && data[i+1] <= 0x39 &&
+			data[i+2] >= 0x81 && data[i+2] <= 0xFE && data[i+3] >= 0x30 && data[i+3] <= 0x39 {
+			return true
+		}
+		// 跳过双字节字符的尾字节
+		i++
+	}
+	return false
+}
@@ -0,0 +1,550 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"graphraggo/internal/global"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	uploadDataFile = "data"
+	uploadMetaFile = "meta.json"
+
+	// MaxChunkSize 单个分片的最大字节数
+	MaxChunkSize = 64 << 20
+)
+
+// UploadSession 断点续传会话
+// 分片按顺序追加到 uploads/<id>/data，offset 为已接收的字节数，中断后从 offset 继续上传
+type UploadSession struct {
+	ID        string    `json:"id"`
+	KB        string    `json:"kb"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`   // 文件总大小
+	SHA256    string    `json:"sha256"` // 完整文件的 sha256，为空时不校验
+	Offset    int64     `json:"offset"` // 已接收的字节数
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// errUploadNotExists 上传会话不存在或已被完成、取消
+var errUploadNotExists = errors.New("not exists")
+
+// uploadErrStatus 会话不存在返回 404，其余（如 meta.json 损坏、无权限）返回 500
+func uploadErrStatus(err error) int {
+	if errors.Is(err, errUploadNotExists) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// uploadLocks 每个会话一把锁，保证同一会话的分片顺序写入
+var uploadLocks sync.Map
+
+// lockUpload 获取会话的锁，会话不存在时返回错误，不为其创建锁
+func lockUpload(id string) (func(), error) {
+	if _, err := ReadUploadSession(id); err != nil {
+		return nil, err
+	}
+	v, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+
+	// 等待期间会话可能已被完成或取消
+	if _, err := os.Stat(uploadPath(id, uploadMetaFile)); err != nil {
+		uploadLocks.CompareAndDelete(id, mu)
+		mu.Unlock()
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("upload session '%s' %w", id, errUploadNotExists)
+		}
+		return nil, err
+	}
+	return mu.Unlock, nil
+}
+
+// uploadPath 获取上传会话文件的绝对路径
+func uploadPath(elem ...string) string {
+	parts := append([]string{global.WorkDir, global.UploadDir}, elem...)
+	return filepath.Join(parts...)
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeUploadSession(s *UploadSession) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(uploadPath(s.ID, uploadMetaFile), data)
+}
+
+// ReadUploadSession 获取上传会话
+func ReadUploadSession(id string) (*UploadSession, error) {
+	if err := checkName(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(uploadPath(id, uploadMetaFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("upload session '%s' %w", id, errUploadNotExists)
+		}
+		return nil, err
+	}
+	s := UploadSession{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid upload session '%s': %w", id, err)
+	}
+	return &s, nil
+}
+
+// CreateUploadSession 创建上传会话，提前检查文件类型与大小
+func CreateUploadSession(kb, filename string, size int64, checksum string) (*UploadSession, int, error) {
+	if err := checkKB(kb); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if size <= 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("size must be positive")
+	}
+	checksum = strings.ToLower(checksum)
+	if checksum != "" {
+		if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+			return nil, http.StatusBadRequest, fmt.Errorf("sha256 '%s' is invalid", checksum)
+		}
+	}
+	if status, err := checkUpload(kb, filename, size); err != nil {
+		return nil, status, err
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	now := time.Now()
+	s := UploadSession{
+		ID:        id,
+		KB:        kb,
+		Filename:  filename,
+		Size:      size,
+		SHA256:    checksum,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(global.UploadSessionTTL),
+	}
+
+	if err := os.MkdirAll(uploadPath(id), os.ModePerm); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if err := os.WriteFile(uploadPath(id, uploadDataFile), nil, 0644); err != nil {
+		os.RemoveAll(uploadPath(id))
+		return nil, http.StatusInternalServerError, err
+	}
+	if err := writeUploadSession(&s); err != nil {
+		os.RemoveAll(uploadPath(id))
+		return nil, http.StatusInternalServerError, err
+	}
+	return &s, http.StatusOK, nil
+}
+
+// WriteUploadChunk 在 offset 处写入分片，offset 必须等于已接收的字节数
+// checksum 为分片的 sha256，为空时不校验
+func WriteUploadChunk(id string, offset int64, chunk []byte, checksum string) (*UploadSession, int, error) {
+	if err := checkName(id); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return nil, uploadErrStatus(err), err
+	}
+	defer unlock()
+
+	s, err := ReadUploadSession(id)
+	if err != nil {
+		return nil, uploadErrStatus(err), err
+	}
+	if offset != s.Offset {
+		return s, http.StatusConflict, fmt.Errorf("offset %d does not match, expected %d", offset, s.Offset)
+	}
+	if offset+int64(len(chunk)) > s.Size {
+		return s, http.StatusBadRequest, fmt.Errorf("chunk exceeds file size %d", s.Size)
+	}
+	if checksum != "" {
+		sum := sha256.Sum256(chunk)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(checksum) {
+			return s, http.StatusBadRequest, fmt.Errorf("chunk checksum mismatch")
+		}
+	}
+
+	f, err := os.OpenFile(uploadPath(id, uploadDataFile), os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	defer f.Close()
+
+	// 上次写入可能在更新 meta.json 前中断，截断到 offset 后重新写入
+	if err := f.Truncate(offset); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if _, err := f.WriteAt(chunk, offset); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	now := time.Now()
+	s.Offset += int64(len(chunk))
+	s.UpdatedAt = now
+	s.ExpiresAt = now.Add(global.UploadSessionTTL)
+	if err := writeUploadSession(s); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return s, http.StatusOK, nil
+}
+
+// CompleteUploadSession 校验完整文件并提交到知识库 input 目录
+func CompleteUploadSession(id string) (*UploadFileResult, int, error) {
+	if err := checkName(id); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return nil, uploadErrStatus(err), err
+	}
+	defer unlock()
+
+	s, err := ReadUploadSession(id)
+	if err != nil {
+		return nil, uploadErrStatus(err), err
+	}
+	if s.Offset != s.Size {
+		return nil, http.StatusConflict, fmt.Errorf("upload incomplete: %d/%d bytes received", s.Offset, s.Size)
+	}
+
+	data := uploadPath(id, uploadDataFile)
+	if s.SHA256 != "" {
+		sum, err := fileSHA256(data)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if sum != s.SHA256 {
+			return nil, http.StatusBadRequest, fmt.Errorf("file checksum mismatch, expected %s, got %s", s.SHA256, sum)
+		}
+	}
+
+	// 上传期间知识库可能已被删除或选项已修改，提交前重新检查
+	if err := checkKB(s.KB); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if status, err := checkUpload(s.KB, s.Filename, s.Size); err != nil {
+		return nil, status, err
+	}
+
+	// 文件内容不合格（4xx）时重试也不会成功，与成功时一样删除会话；
+	// 服务端错误时保留会话，可以再次完成
+	result, status, err := CommitUpload(s.KB, s.Filename, data)
+	if err == nil || status < http.StatusInternalServerError {
+		os.RemoveAll(uploadPath(id))
+		uploadLocks.Delete(id)
+	}
+	if err != nil {
+		return nil, status, err
+	}
+	return result, status, nil
+}
+
+// CancelUploadSession 取消上传会话并删除已上传的数据
+func CancelUploadSession(id string) error {
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := ReadUploadSession(id); err != nil {
+		return err
+	}
+	uploadLocks.Delete(id)
+	return os.RemoveAll(uploadPath(id))
+}
+
+// PurgeExpiredUploads 删除超过有效期的上传会话，返回删除数量
+func PurgeExpiredUploads() (int, error) {
+	files, err := os.ReadDir(uploadPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	n := 0
+	now := time.Now()
+	for _, file := range files {
+		s, err := ReadUploadSession(file.Name())
+		if err != nil || now.Before(s.ExpiresAt) {
+			continue
+		}
+		if err := CancelUploadSession(s.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type UploadApi struct {
+}
+
+func (ua *UploadApi) Register(rg *gin.RouterGroup) {
+	r := rg.Group("/upload")
+
+	r.POST("/create", ua.CreateSession)
+	r.POST("/get", ua.GetSession)
+	r.POST("/chunk", ua.UploadChunk)
+	r.POST("/complete", ua.CompleteSession)
+	r.POST("/cancel", ua.CancelSession)
+}
+
+// CreateSession 创建断点续传会话
+func (ua *UploadApi) CreateSession(c *gin.Context) {
+	type CreateSessionReq struct {
+		KB       string `json:"kb"`
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+		SHA256   string `json:"sha256"`
+	}
+	type CreateSessionRsp struct {
+		BaseRsp
+		Session *UploadSession `json:"session"`
+	}
+
+	req := CreateSessionReq{}
+	rsp := CreateSessionRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	s, status, err := CreateUploadSession(req.KB, req.Filename, req.Size, req.SHA256)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(status, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Session = s
+	c.JSON(http.StatusOK, rsp)
+}
+
+// GetSession 获取上传会话，中断后根据 offset 继续上传
+func (ua *UploadApi) GetSession(c *gin.Context) {
+	type GetSessionReq struct {
+		ID string `json:"id"`
+	}
+	type GetSessionRsp struct {
+		BaseRsp
+		Session *UploadSession `json:"session"`
+	}
+
+	req := GetSessionReq{}
+	rsp := GetSessionRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkName(req.ID); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	s, err := ReadUploadSession(req.ID)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(uploadErrStatus(err), rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.Session = s
+	c.JSON(http.StatusOK, rsp)
+}
+
+// UploadChunk 上传分片，multipart/form-data 字段：id、offset、sha256（可选）、chunk
+func (ua *UploadApi) UploadChunk(c *gin.Context) {
+	type UploadChunkRsp struct {
+		BaseRsp
+		Session *UploadSession `json:"session,omitempty"`
+	}
+
+	rsp := UploadChunkRsp{}
+
+	id := c.PostForm("id")
+	offset, err := strconv.ParseInt(c.PostForm("offset"), 10, 64)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("offset is invalid: %s", err.Error())
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	file, err := c.FormFile("chunk")
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+	if file.Size > MaxChunkSize {
+		rsp.Code = -1
+		rsp.Msg = fmt.Sprintf("chunk size %d exceeds %d", file.Size, MaxChunkSize)
+		c.JSON(http.StatusRequestEntityTooLarge, rsp)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+	defer f.Close()
+	chunk, err := io.ReadAll(f)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusInternalServerError, rsp)
+		return
+	}
+
+	// 出错时同样返回会话，客户端可根据 offset 重试
+	s, status, err := WriteUploadChunk(id, offset, chunk, c.PostForm("sha256"))
+	rsp.Session = s
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(status, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
+
+// CompleteSession 完成上传，校验后提交到知识库
+func (ua *UploadApi) CompleteSession(c *gin.Context) {
+	type CompleteSessionReq struct {
+		ID string `json:"id"`
+	}
+	type CompleteSessionRsp struct {
+		BaseRsp
+		File *UploadFileResult `json:"file"`
+	}
+
+	req := CompleteSessionReq{}
+	rsp := CompleteSessionRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	result, status, err := CompleteUploadSession(req.ID)
+	if err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(status, rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	rsp.File = result
+	c.JSON(http.StatusOK, rsp)
+}
+
+// CancelSession 取消上传
+func (ua *UploadApi) CancelSession(c *gin.Context) {
+	type CancelSessionReq struct {
+		ID string `json:"id"`
+	}
+	type CancelSessionRsp struct {
+		BaseRsp
+	}
+
+	req := CancelSessionReq{}
+	rsp := CancelSessionRsp{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := checkName(req.ID); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(http.StatusBadRequest, rsp)
+		return
+	}
+
+	if err := CancelUploadSession(req.ID); err != nil {
+		rsp.Code = -1
+		rsp.Msg = err.Error()
+		c.JSON(uploadErrStatus(err), rsp)
+		return
+	}
+
+	rsp.Code = 0
+	rsp.Msg = "success"
+	c.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"graphraggo/internal/global"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// kbPath 获取知识库内文件的绝对路径
+func kbPath(kb string, elem ...string) string {
+	parts := append([]string{global.WorkDir, global.KBDir, kb}, elem...)
+	return filepath.Join(parts...)
+}
+
+// checkName 校验知识库、文件等名称，防止路径穿越
+func checkName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("name '%s' is invalid", name)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("name '%s' contains path separator", name)
+	}
+	return nil
+}
+
+// checkKB 校验知识库是否存在
+func checkKB(kb string) error {
+	if err := checkName(kb); err != nil {
+		return err
+	}
+	info, err := os.Stat(kbPath(kb))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("kb '%s' not exists", kb)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入中断导致文件损坏
+func writeFileAtomic(path string, data []byte) error {
+	return writeFileAtomicFrom(path, bytes.NewReader(data))
+}
+
+// writeFileAtomicFrom 将 r 的内容原子写入文件，不在内存中保存完整内容
+func writeFileAtomicFrom(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// copyFile 复制文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyDir 递归复制目录
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		return copyFile(path, target)
+	})
+}
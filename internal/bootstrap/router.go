@@ -44,7 +44,15 @@ func MustInitRouter() *gin.Engine {
 		&api.KGEApi{},
 		&api.KBApi{},
 		&api.DataApi{},
+		&api.GraphApi{},
+		&api.CommunityApi{},
 		&api.QueryApi{},
+		&api.SettingsApi{},
+		&api.PromptApi{},
+		&api.JobApi{},
+		&api.TemplateApi{},
+		&api.TrashApi{},
+		&api.UploadApi{},
 	}
 	for _, rt := range routers {
 		rt.Register(g)
@@ -95,3 +103,47 @@ func MustInitPythonServer() {
 
 	select {}
 }
+
+// InitIndexRuns 将服务中断时未结束的索引记录标记为失败
+func InitIndexRuns() {
+	n, err := api.FailStaleIndexRuns()
+	if err != nil {
+		slog.Error("failed to mark stale index runs",
+			slog.String("err", err.Error()))
+	} else if n > 0 {
+		slog.Info("marked stale index runs as failed",
+			slog.Int("count", n))
+	}
+}
+
+// InitTrashPurger 定期彻底删除回收站中超过保留期限的条目
+func InitTrashPurger() {
+	for {
+		n, err := api.PurgeExpiredTrash()
+		if err != nil {
+			slog.Error("failed to purge trash",
+				slog.String("err", err.Error()))
+		} else if n > 0 {
+			slog.Info("purged expired trash",
+				slog.Int("count", n))
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
+
+// InitUploadPurger 定期删除过期的断点续传会话
+func InitUploadPurger() {
+	for {
+		n, err := api.PurgeExpiredUploads()
+		if err != nil {
+			slog.Error("failed to purge uploads",
+				slog.String("err", err.Error()))
+		} else if n > 0 {
+			slog.Info("purged expired uploads",
+				slog.Int("count", n))
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
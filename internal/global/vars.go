@@ -1,7 +1,12 @@
 package global
 
+import "time"
+
 const (
-	KBDir = "kb"
+	KBDir       = "kb"
+	TemplateDir = "templates"
+	TrashDir    = "trash"
+	UploadDir   = "uploads"
 )
 
 var (
@@ -10,5 +15,13 @@ var (
 	PythonServerPort   int    // Python 服务端口号
 	WorkDir            string // 本项目的绝对路径
 	ExampleSettingFile string // 示例 Settings 文件路径
+	ExamplePromptDir   string // 示例 Prompts 文件夹路径
 	PythonPath         string // Conda 环境下 Python 路径
+
+	ImportDirs []string // 允许导入文件的服务器目录，为空时禁止从服务器目录导入
+
+	TrashRetention   time.Duration // 回收站保留期限，超期自动彻底删除
+	UploadSessionTTL time.Duration // 断点续传会话有效期，超过该时间未上传分片则删除
+
+	IndexVersionRetention int // 每个知识库保留的索引版本数量，超过时删除最早的版本，0 表示不限制
 )
@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -34,6 +37,37 @@ func init() {
 	// ExampleSettingFile
 	global.ExampleSettingFile = fmt.Sprintf("%s/%s/settings-example.yaml", dir, global.KBDir)
 
+	// ExamplePromptDir，使用默认模板的提示词，默认模板不可通过接口修改或删除
+	global.ExamplePromptDir = fmt.Sprintf("%s/%s/default/prompts", dir, global.TemplateDir)
+
+	// TrashRetention，可通过环境变量设置，如 72h
+	global.TrashRetention = 7 * 24 * time.Hour
+	if retention := os.Getenv("GRAPHRAG_TRASH_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil || d <= 0 {
+			panic(fmt.Sprintf("invalid GRAPHRAG_TRASH_RETENTION '%s'", retention))
+		}
+		global.TrashRetention = d
+	}
+
+	// ImportDirs，多个目录以 : 分隔
+	if dirs := os.Getenv("GRAPHRAG_IMPORT_DIRS"); dirs != "" {
+		global.ImportDirs = filepath.SplitList(dirs)
+	}
+
+	// UploadSessionTTL
+	global.UploadSessionTTL = 24 * time.Hour
+
+	// IndexVersionRetention，可通过环境变量设置，0 表示不限制
+	global.IndexVersionRetention = 10
+	if retention := os.Getenv("GRAPHRAG_INDEX_VERSIONS"); retention != "" {
+		n, err := strconv.Atoi(retention)
+		if err != nil || n < 0 {
+			panic(fmt.Sprintf("invalid GRAPHRAG_INDEX_VERSIONS '%s'", retention))
+		}
+		global.IndexVersionRetention = n
+	}
+
 	// PythonPath
 	envName := "graphrag-go"
 	cmd := exec.Command("conda", "run", "-n", envName, "which", "python")
@@ -48,16 +82,30 @@ func init() {
 	fmt.Printf("Port: %d\n", global.Port)
 	fmt.Printf("PythonServerPort: %d\n", global.PythonServerPort)
 	fmt.Printf("ExampleSettingFile: %s\n", global.ExampleSettingFile)
+	fmt.Printf("ExamplePromptDir: %s\n", global.ExamplePromptDir)
 	fmt.Printf("WorkDir: %s\n", global.WorkDir)
 	fmt.Printf("PythonPath: %s\n", global.PythonPath)
+	fmt.Printf("TrashRetention: %s\n", global.TrashRetention)
+	fmt.Printf("UploadSessionTTL: %s\n", global.UploadSessionTTL)
+	fmt.Printf("IndexVersionRetention: %d\n", global.IndexVersionRetention)
+	fmt.Printf("ImportDirs: %v\n", global.ImportDirs)
 }
 
 func main() {
+	// 上次服务中断时未结束的索引不会再结束
+	bootstrap.InitIndexRuns()
+
 	// 启动 Python 服务
 	go func() {
 		bootstrap.MustInitPythonServer()
 	}()
 
+	// 定期清理回收站
+	go bootstrap.InitTrashPurger()
+
+	// 定期清理过期的上传会话
+	go bootstrap.InitUploadPurger()
+
 	r := bootstrap.MustInitRouter()
 
 	if err := r.Run(fmt.Sprintf("%s:%d", global.Host, global.Port)); err != nil {